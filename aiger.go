@@ -0,0 +1,138 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/z"
+)
+
+// WriteAiger writes the sequential circuit `s` as a binary aiger (version
+// 1.9) with outputs `outs` and bad state literals `bads`.
+//
+// The inputs of the aiger are the inputs of `s` in variable order, and the
+// latches are s.Latches in order, so an aiger read from the result has
+// inputs and latches corresponding by index to those of `s`.  Only AND gates
+// in the cone of influence of latches, outputs and bad states are written.
+func WriteAiger(w io.Writer, s *logic.S, outs, bads []z.Lit) error {
+	return writeAiger(w, s, outs, bads, false)
+}
+
+// writeAiger is WriteAiger, writing all the AND gates of `s` if `all`.
+func writeAiger(w io.Writer, s *logic.S, outs, bads []z.Lit, all bool) error {
+	N := s.Len()
+	ids := make([]uint, N) // aiger variable index, by variable
+	ins := sysInputs(s)
+	id := uint(0)
+	for _, m := range ins {
+		id++
+		ids[m.Var()] = id
+	}
+	for _, m := range s.Latches {
+		id++
+		ids[m.Var()] = id
+	}
+	aigLit := func(m z.Lit) uint {
+		if m.Var() == s.T.Var() {
+			if m == s.T {
+				return 1
+			}
+			return 0
+		}
+		a := 2 * ids[m.Var()]
+		if !m.IsPos() {
+			a |= 1
+		}
+		return a
+	}
+	roots := make([]z.Lit, 0, len(s.Latches)+len(outs)+len(bads))
+	for _, m := range s.Latches {
+		roots = append(roots, s.Next(m))
+	}
+	roots = append(roots, outs...)
+	roots = append(roots, bads...)
+	if all {
+		for i := 1; i < N; i++ {
+			if m := z.Var(i).Pos(); s.Type(m) == logic.SAnd {
+				roots = append(roots, m)
+			}
+		}
+	}
+
+	// number AND gates in post order
+	var ands []z.Lit
+	var stk []z.Lit
+	for _, r := range roots {
+		stk = append(stk, r.Var().Pos())
+		for len(stk) > 0 {
+			m := stk[len(stk)-1]
+			if ids[m.Var()] != 0 || s.Type(m) != logic.SAnd {
+				stk = stk[:len(stk)-1]
+				continue
+			}
+			a, b := s.Ins(m)
+			pushed := false
+			for _, c := range [...]z.Lit{a, b} {
+				if ids[c.Var()] == 0 && s.Type(c) == logic.SAnd {
+					stk = append(stk, c.Var().Pos())
+					pushed = true
+				}
+			}
+			if pushed {
+				continue
+			}
+			stk = stk[:len(stk)-1]
+			id++
+			ids[m.Var()] = id
+			ands = append(ands, m)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "aig %d %d %d %d %d", id, len(ins), len(s.Latches), len(outs), len(ands))
+	if len(bads) != 0 {
+		fmt.Fprintf(bw, " %d", len(bads))
+	}
+	fmt.Fprintf(bw, "\n")
+	for _, m := range s.Latches {
+		fmt.Fprintf(bw, "%d", aigLit(s.Next(m)))
+		switch s.Init(m) {
+		case s.F:
+		case s.T:
+			fmt.Fprintf(bw, " 1")
+		default:
+			fmt.Fprintf(bw, " %d", aigLit(m))
+		}
+		fmt.Fprintf(bw, "\n")
+	}
+	for _, m := range outs {
+		fmt.Fprintf(bw, "%d\n", aigLit(m))
+	}
+	for _, m := range bads {
+		fmt.Fprintf(bw, "%d\n", aigLit(m))
+	}
+	for _, m := range ands {
+		a, b := s.Ins(m)
+		lhs, r0, r1 := aigLit(m), aigLit(a), aigLit(b)
+		if r0 < r1 {
+			r0, r1 = r1, r0
+		}
+		writeDelta(bw, lhs-r0)
+		writeDelta(bw, r0-r1)
+	}
+	fmt.Fprintf(bw, "c\nwritten by reach\n")
+	return bw.Flush()
+}
+
+func writeDelta(w *bufio.Writer, x uint) {
+	for x&^0x7f != 0 {
+		w.WriteByte(byte(x&0x7f | 0x80))
+		x >>= 7
+	}
+	w.WriteByte(byte(x))
+}
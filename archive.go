@@ -0,0 +1,208 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ArchiveExt is the conventional extension of output archives.
+const ArchiveExt = ".reach"
+
+// WriteArchive writes `o` to `w` as a single file archive, which is
+// a gzip compressed tar file containing a copy of the aiger, the manifest
+// and the files of the results listed in the manifest.  Unlike an output
+// directory, an archive does not refer to the aiger by a symlink, so it may
+// be moved between machines.
+//
+// If `o` has no manifest, as for output directories written by earlier
+// versions of reach, then WriteArchive first writes one.
+func (o *Output) WriteArchive(w io.Writer) error {
+	man, err := o.Manifest()
+	if err != nil {
+		return err
+	}
+	if man == nil {
+		if err := o.update(func() error { return nil }); err != nil {
+			return err
+		}
+		if man, err = o.Manifest(); err != nil {
+			return err
+		}
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := archiveFile(tw, aigName, o.AigerPath()); err != nil {
+		return err
+	}
+	if err := archiveFile(tw, manifestName, filepath.Join(o.root, manifestName)); err != nil {
+		return err
+	}
+	for _, r := range man.Results {
+		for _, mf := range r.Files {
+			if err := archiveFile(tw, mf.Name, filepath.Join(o.root, mf.Name)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// OpenArchive opens an archive written by WriteArchive, as OpenOutput
+// opens an output directory.
+//
+// The archive is extracted to a temporary directory, which is the RootDir of
+// the returned output and which is removed by Close.  Results and
+// certificates stored in the output, and minimized invariants, are written
+// back to the archive.  Unlike output directories, archives are not locked, so
+// only one process should store results in an archive at a time.
+func OpenArchive(p string) (*Output, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir, err := ioutil.TempDir("", "reach")
+	if err != nil {
+		return nil, err
+	}
+	if err := extractArchive(dir, f); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("%w: %s: %s", ErrOutputCorrupt, p, err)
+	}
+	out, err := OpenOutput(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	out.archive = p
+	return out, nil
+}
+
+// IsArchive returns whether the file with path `p` is an output archive,
+// rather than an output directory.
+func IsArchive(p string) bool {
+	f, err := os.Open(p)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		return false
+	}
+	hd := make([]byte, 2)
+	if _, err := io.ReadFull(f, hd); err != nil {
+		return false
+	}
+	return bytes.Equal(hd, []byte{0x1f, 0x8b})
+}
+
+// Archive returns the path of the archive from which `o` was opened, or
+// the empty string if `o` is an output directory.
+func (o *Output) Archive() string {
+	return o.archive
+}
+
+// Close releases the resources of `o`.  For outputs opened from an
+// archive, Close removes the temporary directory to which the archive was
+// extracted.  For output directories, Close does nothing.
+func (o *Output) Close() error {
+	if o.archive == "" {
+		return nil
+	}
+	return os.RemoveAll(o.root)
+}
+
+// storeArchive rewrites the archive from which `o` was opened, if any.
+func (o *Output) storeArchive() error {
+	if o.archive == "" {
+		return nil
+	}
+	_, err := atomicWrite(o.archive, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		if err := o.WriteArchive(bw); err != nil {
+			return err
+		}
+		return bw.Flush()
+	})
+	return err
+}
+
+func archiveFile(tw *tar.Writer, name, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		Format:  tar.FormatPAX}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// extractArchive extracts the archive read from `r` in directory `dir`.
+// Only regular files without directory components are accepted.
+func extractArchive(dir string, r io.Reader) error {
+	gz, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	seen := make(map[string]bool)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := hdr.Name
+		if hdr.Typeflag != tar.TypeReg || name != filepath.Base(name) || name == "." || name == ".." {
+			return fmt.Errorf("unexpected entry %q", name)
+		}
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		seen[name] = true
+	}
+	for _, name := range []string{aigName, manifestName} {
+		if !seen[name] {
+			return fmt.Errorf("no %s", name)
+		}
+	}
+	return nil
+}
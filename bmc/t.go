@@ -4,7 +4,8 @@
 package bmc
 
 import (
-	"log"
+	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-air/gini"
@@ -18,6 +19,15 @@ type bmcBad struct {
 	*reach.Result
 	Timed     bool
 	timeSpent time.Duration
+	satCalls  int64
+}
+
+// result returns a copy of the result of `b` with its current stats, as
+// results given to an output should not be modified afterwards.
+func (b *bmcBad) result() *reach.Result {
+	r := *b.Result
+	r.Stats = map[string]int64{"SatCalls": b.satCalls}
+	return &r
 }
 
 func (b *bmcBad) remaining() time.Duration {
@@ -33,6 +43,8 @@ type T struct {
 	deadLine time.Time
 	maxDepth int
 	trace    bool
+	out      *reach.Output
+	stop     int32 // set by Stop
 }
 
 // New creates a new bounded model checker for bad states `bads` occuring in `s`.
@@ -45,7 +57,7 @@ func New(s *logic.S, bads ...z.Lit) *T {
 	res := &T{sat: gini.NewVc(s.Len()*11, s.Len()*3*11), roll: logic.NewRoll(s)}
 	res.bads = make(map[z.Lit]*bmcBad, len(bads))
 	for _, m := range bads {
-		res.bads[m] = &bmcBad{Result: &reach.Result{M: m}}
+		res.bads[m] = &bmcBad{Result: &reach.Result{M: m, Engine: "bmc"}}
 	}
 	res.maxDepth = 1 << 30
 	res.trace = true
@@ -57,6 +69,13 @@ func (t *T) SetMaxDepth(d int) {
 	t.maxDepth = d
 }
 
+// SetOutput sets an output in which each reachable bad state is stored as
+// soon as it is found by Try, so that it is kept even if the process is
+// stopped before Try returns.
+func (t *T) SetOutput(o *reach.Output) {
+	t.out = o
+}
+
 // SetBadTimeout sets a timeout for the bad state `bad`.
 //
 // SetBadTimeout panics if `bad` was not supplied as a bad state in
@@ -80,26 +99,32 @@ func (t *T) DistributeTimeout(d time.Duration) {
 // specified earlier (per bad timeout, max depth) and within
 // duration `dur`.
 //
-// Run returns the number of reachable bad states found.
-func (t *T) Try(dur time.Duration) int {
+// Try returns the number of reachable bad states found.  If a trace to a
+// bad state is not coherent with the circuit, then the bad state is still
+// counted, without a trace, and Try returns a non-nil error wrapping
+// reach.ErrTraceIncoherent, for the first such bad state.  If an output was
+// given to SetOutput and storing a result fails, Try returns the first such
+// error.
+func (t *T) Try(dur time.Duration) (int, error) {
 	t.deadLine = time.Now().Add(dur)
 	found := 0
 	depth := 0
 	var mark []int8
+	var err error
 	for found < len(t.bads) {
 		if depth > t.maxDepth {
-			return found
+			return found, err
 		}
-		if time.Until(t.deadLine) <= 0 {
-			return found
+		if time.Until(t.deadLine) <= 0 || t.stopped() {
+			return found, err
 		}
 		for k, v := range t.bads {
 			if v.IsSolved() {
 				continue
 			}
 			dur := time.Until(t.deadLine)
-			if dur < 0 {
-				return found
+			if dur < 0 || t.stopped() {
+				return found, err
 			}
 			if v.Timed {
 				if d := v.remaining(); d < dur {
@@ -115,17 +140,23 @@ func (t *T) Try(dur time.Duration) int {
 			t.sat.Assume(m)
 			dur -= time.Since(start) // include unrolling time
 			res := t.sat.Try(dur)
+			v.satCalls++
 			if res == 1 {
 				found++
 				v.Status = 1
 				v.Depth = depth
 				if t.trace {
-					tr, err := reach.NewTraceBmc(t.roll, t.sat, k)
-					if err != nil {
-						log.Fatal(err)
+					tr, errs := reach.NewTraceBmc(t.roll, t.sat, k)
+					if errs != nil && err == nil {
+						err = fmt.Errorf("bad %s: %w", k, errs[0])
 					}
 					v.Trace = tr
 				}
+				if t.out != nil {
+					if serr := t.out.StoreResult(v.result()); serr != nil && err == nil {
+						err = fmt.Errorf("storing bad %s: %w", k, serr)
+					}
+				}
 				continue
 			}
 			if v.Timed {
@@ -137,13 +168,25 @@ func (t *T) Try(dur time.Duration) int {
 		}
 		depth++
 	}
-	return found
+	return found, err
+}
+
+// Stop asks Try, running in another goroutine, to return before its next
+// SAT call, as if its time limit were reached.  A SAT call in progress is
+// not interrupted.  Once Stop is called, subsequent calls to Try return
+// immediately.
+func (t *T) Stop() {
+	atomic.StoreInt32(&t.stop, 1)
+}
+
+func (t *T) stopped() bool {
+	return atomic.LoadInt32(&t.stop) != 0
 }
 
 // FillOutput fills the output object with
 // bads and traces
 func (t *T) FillOutput(dst *reach.Output) {
 	for _, b := range t.bads {
-		dst.AppendResult(b.Result)
+		dst.AppendResult(b.result())
 	}
 }
@@ -0,0 +1,170 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-air/gini"
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/logic/aiger"
+	"github.com/go-air/gini/z"
+)
+
+// AigerCertificate adds to `s` the bad state literal of a witness circuit
+// certifying that `inv` shows `bad` is unreachable.
+//
+// The witness circuit is `s` with the single bad state literal
+//
+//	bad | !inv
+//
+// which is unreachable iff `bad` is, and for which the conjunction of the
+// clauses of `inv` (that is, the negation of the witness bad state
+// literal) is an inductive invariant by itself.  This is the form of
+// certificate checked by Certifaiger for witness circuits with the same
+// inputs and latches as the model.
+func AigerCertificate(s *logic.S, bad z.Lit, inv Invariant) z.Lit {
+	acc := s.T
+	inv.Forall(func(_ int, c []z.Lit) {
+		acc = s.And(acc, s.Ors(c...))
+	})
+	return s.Or(bad, acc.Not())
+}
+
+// WriteAigerCertificate writes the witness circuit of AigerCertificate as a
+// binary aiger to `w`.  WriteAigerCertificate may add nodes to `s`.
+func WriteAigerCertificate(w io.Writer, s *logic.S, bad z.Lit, inv Invariant) error {
+	cbad := AigerCertificate(s, bad, inv)
+	return WriteAiger(w, s, nil, []z.Lit{cbad})
+}
+
+// CheckAigerCertificate checks that the witness circuit `cert` certifies
+// that `bad` is unreachable in `s`.
+//
+// `cert` must have the same inputs and latches as `s`, in the order
+// written by WriteAiger, and a single bad state literal (or output).
+// CheckAigerCertificate checks
+//
+//  1. reset: the latches of `cert` and `s` have the same initial values;
+//  2. transition: the latches of `cert` and `s` have equivalent next state
+//     functions;
+//  3. property: every state satisfying `bad` satisfies the bad state
+//     literal of `cert`; and
+//  4. the negation of the bad state literal of `cert` is an inductive
+//     invariant, as with CheckInvariant.  As it may depend on the inputs,
+//     it must hold for all inputs of the successor states.
+//
+// CheckAigerCertificate returns the list of errors found, which is empty iff
+// all checks succeed within `dur`.  Failures of the first three checks wrap
+// ErrCertificate, and the last are as for CheckInvariant.
+// CheckAigerCertificate may add nodes to `s`.
+func CheckAigerCertificate(s *logic.S, bad z.Lit, cert *aiger.T, dur time.Duration) []error {
+	deadline := time.Now().Add(dur)
+	cs := cert.Sys()
+	ins := sysInputs(s)
+	if len(cert.Inputs) != len(ins) {
+		return []error{fmt.Errorf("%w: %d inputs for %d", ErrCertificate, len(cert.Inputs), len(ins))}
+	}
+	if len(cs.Latches) != len(s.Latches) {
+		return []error{fmt.Errorf("%w: %d latches for %d", ErrCertificate, len(cs.Latches), len(s.Latches))}
+	}
+	cbads := AigerBad(cert)
+	if len(cbads) != 1 {
+		return []error{fmt.Errorf("%w: %d bad states", ErrCertificate, len(cbads))}
+	}
+	vmap := make([]z.Lit, cs.Len())
+	vmap[cs.T.Var()] = s.T
+	for i, m := range cert.Inputs {
+		vmap[m.Var()] = ins[i]
+	}
+	for i, m := range cs.Latches {
+		vmap[m.Var()] = s.Latches[i]
+	}
+	importAnds(s, cs, vmap)
+
+	var errs []error
+	for i, m := range cs.Latches {
+		sm := s.Latches[i]
+		if mapLit(vmap, cs.Init(m)) != s.Init(sm) {
+			errs = append(errs, fmt.Errorf("%w: reset of latch %d differs", ErrCertificate, i))
+		}
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+
+	xors := make([]z.Lit, len(cs.Latches))
+	for i, m := range cs.Latches {
+		xors[i] = s.Xor(mapLit(vmap, cs.Next(m)), s.Next(s.Latches[i]))
+	}
+	cbad := mapLit(vmap, cbads[0])
+	sat := gini.New()
+	s.ToCnf(sat)
+	try := func() int {
+		res := sat.Try(time.Until(deadline))
+		if res == 0 {
+			errs = append(errs, ErrTimeout)
+		}
+		return res
+	}
+	for i, x := range xors {
+		sat.Assume(x)
+		switch try() {
+		case 0:
+			return errs
+		case 1:
+			errs = append(errs, fmt.Errorf("%w: next state of latch %d differs", ErrCertificate, i))
+		}
+	}
+	sat.Assume(bad, cbad.Not())
+	switch try() {
+	case 0:
+		return errs
+	case 1:
+		errs = append(errs, fmt.Errorf("%w: %s does not imply certificate bad state", ErrCertificate, bad))
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	return failureErrors(Invariant{cbad.Not(), 0}.Check(s, cbad, time.Until(deadline)))
+}
+
+// sysInputs returns the inputs of `s` in variable order.
+func sysInputs(s *logic.S) []z.Lit {
+	var ins []z.Lit
+	for i := 2; i < s.Len(); i++ {
+		m := z.Var(i).Pos()
+		if s.Type(m) == logic.SInput {
+			ins = append(ins, m)
+		}
+	}
+	return ins
+}
+
+// importAnds adds the AND gates of `src` to `dst`, where `vmap` maps the
+// variables of `src` to literals of `dst` and has an entry for every input
+// and latch of `src`.  The entries of the AND gates are filled in.
+func importAnds(dst, src *logic.S, vmap []z.Lit) {
+	for i := 2; i < src.Len(); i++ {
+		m := z.Var(i).Pos()
+		if src.Type(m) != logic.SAnd {
+			continue
+		}
+		a, b := src.Ins(m)
+		vmap[i] = dst.And(mapLit(vmap, a), mapLit(vmap, b))
+	}
+}
+
+func mapLit(vmap []z.Lit, m z.Lit) z.Lit {
+	if m == z.LitNull {
+		return m
+	}
+	res := vmap[m.Var()]
+	if !m.IsPos() {
+		res = res.Not()
+	}
+	return res
+}
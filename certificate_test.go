@@ -0,0 +1,65 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/go-air/gini/logic/aiger"
+	"github.com/go-air/gini/z"
+)
+
+func TestAigerCertificate(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		inv   func(a, b, c, bad z.Lit) []z.Lit
+		reset bool
+		ok    bool
+	}{
+		{"notb", func(a, b, c, bad z.Lit) []z.Lit { return []z.Lit{b.Not(), 0} }, false, true},
+		{"nota", func(a, b, c, bad z.Lit) []z.Lit { return []z.Lit{a.Not(), 0, bad.Not(), 0} }, false, true},
+		{"init", func(a, b, c, bad z.Lit) []z.Lit { return []z.Lit{b, 0} }, false, false},
+		{"consecution", func(a, b, c, bad z.Lit) []z.Lit { return []z.Lit{c.Not(), 0, b.Not(), 0} }, false, false},
+		{"reset", func(a, b, c, bad z.Lit) []z.Lit { return []z.Lit{b.Not(), 0} }, true, false},
+	} {
+		s, a, b, c, bad := genSafe()
+		buf := bytes.NewBuffer(nil)
+		if err := WriteAigerCertificate(buf, s.Copy(), bad, tc.inv(a, b, c, bad)); err != nil {
+			t.Fatal(err)
+		}
+		cert, err := aiger.ReadBinary(buf)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		if tc.reset {
+			s.SetInit(c, s.T)
+		}
+		errs := CheckAigerCertificate(s, bad, cert, time.Second)
+		if tc.ok && len(errs) != 0 {
+			t.Errorf("%s: unexpected errors %v", tc.name, errs)
+		}
+		if !tc.ok && len(errs) == 0 {
+			t.Errorf("%s: expected errors", tc.name)
+		}
+	}
+}
+
+func TestAigerCertificateInputs(t *testing.T) {
+	s, _, _, bad := genInputBad()
+	// the circuit itself, with its reachable bad state over inputs, is not
+	// a witness circuit.
+	buf := bytes.NewBuffer(nil)
+	if err := WriteAiger(buf, s.Copy(), nil, []z.Lit{bad}); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := aiger.ReadBinary(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := CheckAigerCertificate(s, bad, cert, time.Second); len(errs) == 0 {
+		t.Errorf("certified reachable bad state over inputs")
+	}
+}
@@ -56,6 +56,7 @@ func doAag(cmd *subCmd, args []string) {
 	}
 	if err := doAagArg(cmd, flags.Arg(0)); err != nil {
 		log.Printf("error writing aag for '%s': %s\n", flags.Arg(0), err.Error())
+		emitError(cmd, flags.Arg(0), false, err)
 	}
 }
 
@@ -65,25 +66,28 @@ func doAagArg(cmd *subCmd, arg string) error {
 	if os.IsNotExist(err) {
 		return err
 	}
-	if !st.IsDir() {
+	if !st.IsDir() && !reach.IsArchive(arg) {
 		flags.SetOutput(os.Stderr)
 		flags.Usage()
 		fmt.Fprintf(os.Stderr, `cannot output aag, need reach output dir.\n`)
 		os.Exit(2)
 	}
-	return doAagOutput(arg)
+	return doAagOutput(cmd, arg)
 }
 
-func doAagOutput(arg string) error {
+func doAagOutput(cmd *subCmd, arg string) error {
 	out, err := reach.OpenOutput(arg)
 	if err != nil {
 		return err
 	}
+	defer out.Close()
+	emit(&event{Event: "start", Cmd: cmd.Name, Output: arg})
 	aig, err := out.Aiger()
 	if err != nil {
 		return err
 	}
-	w, e := aagWriter()
+	ev := &event{Event: "result", Cmd: cmd.Name, Output: arg}
+	w, e := aagWriter(ev)
 	if e != nil {
 		return e
 	}
@@ -91,11 +95,27 @@ func doAagOutput(arg string) error {
 	outs = append(outs, aig.Outputs...)
 	outs = append(outs, aig.Bad...)
 	trans := aiger.MakeFor(aig.Sys(), outs...)
-	return trans.WriteAscii(w)
+	err = trans.WriteAscii(w)
+	if w == os.Stdout {
+		return err
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if _, ok := w.(*eventWriter); !ok && err == nil {
+		ev.File = *aagOpts.outPath
+		emit(ev)
+	}
+	return err
 }
 
-func aagWriter() (io.WriteCloser, error) {
+// aagWriter opens the output given by -o.  With -json, the aiger which would
+// be written to stdout is emitted with `e` when the output is closed.
+func aagWriter(e *event) (io.WriteCloser, error) {
 	if *aagOpts.outPath == "-" || *aagOpts.outPath == "" {
+		if *jsonEvents {
+			return &eventWriter{e: e}, nil
+		}
 		return os.Stdout, nil
 	}
 	return os.OpenFile(*aagOpts.outPath, os.O_WRONLY|os.O_CREATE, 0644)
@@ -56,6 +56,7 @@ func doAig(cmd *subCmd, args []string) {
 	}
 	if err := doAigArg(cmd, flags.Arg(0)); err != nil {
 		log.Printf("error writing aig for '%s': %s\n", flags.Arg(0), err.Error())
+		emitError(cmd, flags.Arg(0), false, err)
 	}
 }
 
@@ -65,25 +66,28 @@ func doAigArg(cmd *subCmd, arg string) error {
 	if os.IsNotExist(err) {
 		return err
 	}
-	if !st.IsDir() {
+	if !st.IsDir() && !reach.IsArchive(arg) {
 		flags.SetOutput(os.Stderr)
 		flags.Usage()
 		fmt.Fprintf(os.Stderr, `cannot output aig, need reach output dir.\n`)
 		os.Exit(2)
 	}
-	return doAigOutput(arg)
+	return doAigOutput(cmd, arg)
 }
 
-func doAigOutput(arg string) error {
+func doAigOutput(cmd *subCmd, arg string) error {
 	out, err := reach.OpenOutput(arg)
 	if err != nil {
 		return err
 	}
+	defer out.Close()
+	emit(&event{Event: "start", Cmd: cmd.Name, Output: arg})
 	aig, err := out.Aiger()
 	if err != nil {
 		return err
 	}
-	w, e := aigWriter()
+	ev := &event{Event: "result", Cmd: cmd.Name, Output: arg}
+	w, e := aigWriter(ev)
 	if e != nil {
 		return e
 	}
@@ -91,11 +95,27 @@ func doAigOutput(arg string) error {
 	outs = append(outs, aig.Outputs...)
 	outs = append(outs, aig.Bad...)
 	trans := aiger.MakeFor(aig.Sys(), outs...)
-	return trans.WriteBinary(w)
+	err = trans.WriteBinary(w)
+	if w == os.Stdout {
+		return err
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if _, ok := w.(*eventWriter); !ok && err == nil {
+		ev.File = *aigOpts.outPath
+		emit(ev)
+	}
+	return err
 }
 
-func aigWriter() (io.WriteCloser, error) {
+// aigWriter opens the output given by -o.  With -json, the aiger which would
+// be written to stdout is emitted with `e` when the output is closed.
+func aigWriter(e *event) (io.WriteCloser, error) {
 	if *aigOpts.outPath == "-" || *aigOpts.outPath == "" {
+		if *jsonEvents {
+			return &eventWriter{e: e}, nil
+		}
 		return os.Stdout, nil
 	}
 	return os.OpenFile(*aigOpts.outPath, os.O_WRONLY|os.O_CREATE, 0644)
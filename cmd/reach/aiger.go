@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/go-air/gini/logic/aiger"
 	"github.com/go-air/gini/z"
+	"github.com/go-air/reach"
 )
 
 func readAiger(fn string) (*aiger.T, error) {
@@ -29,8 +32,51 @@ func readAiger(fn string) (*aiger.T, error) {
 }
 
 func aigerBad(g *aiger.T) []z.Lit {
-	if len(g.Bad) == 0 {
-		return g.Outputs
+	return reach.AigerBad(g)
+}
+
+// findAigers returns the aigers (.aig or .aag files) given by `args`, which
+// may be files or directories containing aigers.
+func findAigers(args []string) ([]string, error) {
+	var fns []string
+	for _, arg := range args {
+		err := filepath.Walk(arg, func(p string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			switch filepath.Ext(p) {
+			case ".aig", ".aag":
+				if !fi.IsDir() {
+					fns = append(fns, p)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fns, nil
+}
+
+var propsDoc = "comma separated indices of the bad states to check (default all)."
+
+// selectBad returns the bad states of `bad` with the comma separated
+// indices `sel`, or all of them if `sel` is empty.
+func selectBad(bad []z.Lit, sel string) ([]z.Lit, error) {
+	if sel == "" {
+		return bad, nil
+	}
+	var res []z.Lit
+	for _, f := range strings.Split(sel, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, fmt.Errorf("bad state index '%s': %w", f, err)
+		}
+		if i < 0 || i >= len(bad) {
+			return nil, fmt.Errorf("bad state index %d out of range [0..%d)", i, len(bad))
+		}
+		res = append(res, bad[i])
 	}
-	return g.Bad
+	return res, nil
 }
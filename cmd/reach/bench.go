@@ -0,0 +1,397 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-air/reach"
+)
+
+var benchCmd = &subCmd{
+	Name:  "bench",
+	Flags: flag.NewFlagSet("bench", flag.ExitOnError),
+	Run:   doBench,
+	Init:  initBench,
+	Usage: "reach bench [opts] <dir> <engine> [engine opts]",
+	Short: `bench runs an engine over a directory of aigers.`,
+	Long: `
+bench runs the engine iic, bmc or sim with the given engine options on each
+aiger (.aig or .aag) under <dir>, with -j jobs at a time.  Each job is a
+separate reach process, so a crash or exhausted memory only affects its own
+aiger.  The output of a job is stored under the directory -o, in the same
+relative location as the aiger in <dir>, with the messages of the process
+in <output>.log.
+
+-time limits the wall-clock time of each job.  When it is exceeded, the job
+is sent SIGTERM, so that it stores the results found so far, and is killed
+if it has not exited 5 seconds later.  -mem limits the address space of each
+job, in megabytes.  The engine's own time limit (-dur) should be smaller
+than -time.
+
+A row is appended to the results file -res for each result of each job, as
+soon as the job ends.  Rows have the fields of results shown by "reach info"
+(M, Status, Depth, Dur, Engine, Options, Seed, Version, AigerHash, TraceLen,
+InvClauses), with durations in nanoseconds as in the stored json, preceded
+by the fields of the job: Aiger, Output, Exit (the exit status, or -1 if the
+job was killed by a signal), Error, Timeout, Wall and MaxRSS (in kilobytes).
+A job with no results has one row with the job fields only.  The results
+file is CSV, or newline delimited json if its name ends in ".json".
+
+bench is resumable: aigers which already have rows in the results file are
+skipped, and outputs left by interrupted jobs are merged into.
+`}
+
+var benchOpts = struct {
+	Dir  *string
+	J    *int
+	Time *time.Duration
+	Mem  *int64
+	Res  *string
+}{}
+
+// memLimitArg is the global option with which bench and run pass the memory
+// limit, in megabytes, to their jobs.  It is not a flag of reachFlags, so
+// that it is not listed in the usage.
+const memLimitArg = "-memlimit="
+
+// memLimitArgs returns the global arguments limiting the memory of a job to
+// `mb` megabytes, none if `mb` is not positive.
+func memLimitArgs(mb int64) []string {
+	if mb <= 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s%d", memLimitArg, mb)}
+}
+
+// parseMemLimit removes the memory limit option from the global arguments
+// `gargs`, returning the remaining arguments and the limit, or 0 if there
+// is none.
+func parseMemLimit(gargs []string) ([]string, int64, error) {
+	var rest []string
+	var mb int64
+	for _, arg := range gargs {
+		if !strings.HasPrefix(arg, memLimitArg) {
+			rest = append(rest, arg)
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimPrefix(arg, memLimitArg), 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", arg, err)
+		}
+		mb = n
+	}
+	return rest, mb, nil
+}
+
+// benchGrace is the time given to a job to store its results after its time
+// limit.
+const benchGrace = 5 * time.Second
+
+func initBench(cmd *subCmd) {
+	flags := cmd.Flags
+	benchOpts.Dir = flags.String("o", "bench", "output directory.")
+	benchOpts.J = flags.Int("j", 0, "number of concurrent jobs (default number of CPUs).")
+	benchOpts.Time = flags.Duration("time", time.Minute, "wall-clock limit per job.")
+	benchOpts.Mem = flags.Int64("mem", 0, "memory limit per job in megabytes (0 for none).")
+	benchOpts.Res = flags.String("res", "", "results file (default <o>/results.csv).")
+	flags.Usage = func() {
+		fmt.Println(cmd.Usage)
+		flags.PrintDefaults()
+		fmt.Println(cmd.Long)
+	}
+}
+
+// benchRow is a row of the results file.
+type benchRow struct {
+	Aiger   string
+	Output  string
+	Exit    int
+	Error   string `json:",omitempty"`
+	Timeout bool
+	Wall    time.Duration
+	MaxRSS  int64
+	*reach.Result
+}
+
+var benchFields = []string{"Aiger", "Output", "Exit", "Error", "Timeout", "Wall", "MaxRSS",
+	"M", "Status", "Depth", "Dur", "Engine", "Options", "Seed", "Version", "AigerHash",
+	"TraceLen", "InvClauses"}
+
+func (r *benchRow) csv() []string {
+	rec := []string{r.Aiger, r.Output, strconv.Itoa(r.Exit), r.Error,
+		strconv.FormatBool(r.Timeout), strconv.FormatInt(int64(r.Wall), 10),
+		strconv.FormatInt(r.MaxRSS, 10)}
+	if r.Result == nil {
+		return append(rec, make([]string, len(benchFields)-len(rec))...)
+	}
+	b := r.Result
+	return append(rec, fmt.Sprintf("%d", b.M), strconv.Itoa(b.Status), strconv.Itoa(b.Depth),
+		strconv.FormatInt(int64(b.Dur), 10), b.Engine, b.Options, strconv.FormatInt(b.Seed, 10),
+		b.Version, b.AigerHash, strconv.Itoa(b.TraceLen), strconv.Itoa(b.InvClauses))
+}
+
+func doBench(cmd *subCmd, args []string) {
+	flags := cmd.Flags
+	flags.Parse(args)
+	if flags.NArg() < 2 {
+		flags.SetOutput(os.Stderr)
+		flags.Usage()
+		os.Exit(2)
+	}
+	dir, engine := flags.Arg(0), flags.Arg(1)
+	switch engine {
+	case "iic", "bmc", "sim":
+	default:
+		log.Fatalf("bench: unknown engine '%s'", engine)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	res := *benchOpts.Res
+	if res == "" {
+		res = filepath.Join(*benchOpts.Dir, "results.csv")
+	}
+	if err := os.MkdirAll(*benchOpts.Dir, 0755); err != nil {
+		log.Fatal(err)
+	}
+	done, err := benchDone(res)
+	if err != nil {
+		log.Fatalf("error reading results '%s': %s", res, err)
+	}
+	fns, err := findAigers([]string{dir})
+	if err != nil {
+		log.Fatal(err)
+	}
+	var aigs []string
+	for _, p := range fns {
+		if !done[p] {
+			aigs = append(aigs, p)
+		}
+	}
+	w, err := newBenchWriter(res)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer w.close()
+	log.Printf("bench: %d jobs, %d done before", len(aigs), len(done))
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < numJobs(*benchOpts.J); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				rows := benchJob(exe, dir, p, flags.Args()[1:])
+				if err := w.write(rows); err != nil {
+					log.Printf("error writing results: %s", err)
+				}
+			}
+		}()
+	}
+	for _, p := range aigs {
+		jobs <- p
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// benchJob runs the engine with arguments `eargs` on aiger `p` under `dir`
+// in a subprocess and returns the rows of its results.
+func benchJob(exe, dir, p string, eargs []string) []*benchRow {
+	rel, err := filepath.Rel(dir, p)
+	if err != nil {
+		rel = filepath.Base(p)
+	}
+	odir := filepath.Join(*benchOpts.Dir, filepath.Dir(rel))
+	base := filepath.Base(p)
+	opath := filepath.Join(odir, strings.TrimSuffix(base, filepath.Ext(base)))
+	row := &benchRow{Aiger: p, Output: opath}
+	if err := os.MkdirAll(odir, 0755); err != nil {
+		row.Exit, row.Error = -1, err.Error()
+		return []*benchRow{row}
+	}
+	args := append(memLimitArgs(*benchOpts.Mem), "-merge", "-trace", *traceFmtName)
+	args = append(args, eargs...)
+	args = append(args, "-o", odir, p)
+	c := exec.Command(exe, args...)
+	logf, err := os.Create(opath + ".log")
+	if err != nil {
+		row.Exit, row.Error = -1, err.Error()
+		return []*benchRow{row}
+	}
+	defer logf.Close()
+	c.Stdout, c.Stderr = logf, logf
+	start := time.Now()
+	row.Timeout, err = runLimited(c, *benchOpts.Time)
+	row.Wall = time.Since(start)
+	if c.ProcessState == nil {
+		row.Exit, row.Error = -1, err.Error()
+		return []*benchRow{row}
+	}
+	row.Exit = c.ProcessState.ExitCode()
+	row.MaxRSS = maxRSS(c.ProcessState)
+	if err != nil {
+		row.Error = err.Error()
+	}
+	log.Printf("bench: %s: %s in %s", p, c.ProcessState, row.Wall.Round(time.Millisecond))
+	out, err := reach.OpenOutput(opath)
+	if err != nil {
+		if row.Error == "" {
+			row.Error = err.Error()
+		}
+		return []*benchRow{row}
+	}
+	defer out.Close()
+	var rows []*benchRow
+	for _, b := range out.Results() {
+		r := *row
+		r.Result = b
+		rows = append(rows, &r)
+	}
+	if len(rows) == 0 {
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// runLimited runs `c` and waits for it to exit.  If it runs longer than
+// `limit`, it is sent SIGTERM, so that it stores the results found so far,
+// and is killed if it has not exited benchGrace later.  A limit which is not
+// positive does not limit.  runLimited returns whether the limit was
+// exceeded.
+func runLimited(c *exec.Cmd, limit time.Duration) (bool, error) {
+	if err := c.Start(); err != nil {
+		return false, err
+	}
+	if limit <= 0 {
+		return false, c.Wait()
+	}
+	var timeout bool
+	var mu sync.Mutex
+	term := time.AfterFunc(limit, func() {
+		mu.Lock()
+		timeout = true
+		mu.Unlock()
+		terminate(c.Process)
+	})
+	kill := time.AfterFunc(limit+benchGrace, func() {
+		c.Process.Kill()
+	})
+	err := c.Wait()
+	term.Stop()
+	kill.Stop()
+	mu.Lock()
+	defer mu.Unlock()
+	return timeout, err
+}
+
+// benchWriter appends rows to the results file.
+type benchWriter struct {
+	mu   sync.Mutex
+	f    *os.File
+	json bool
+}
+
+func newBenchWriter(p string) (*benchWriter, error) {
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	w := &benchWriter{f: f, json: filepath.Ext(p) == ".json"}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.Size() == 0 && !w.json {
+		if err := w.writeCSV(benchFields); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+func (w *benchWriter) write(rows []*benchRow) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, r := range rows {
+		var err error
+		if w.json {
+			err = json.NewEncoder(w.f).Encode(r)
+		} else {
+			err = w.writeCSV(r.csv())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return w.f.Sync()
+}
+
+func (w *benchWriter) writeCSV(rec []string) error {
+	cw := csv.NewWriter(w.f)
+	cw.Write(rec)
+	cw.Flush()
+	return cw.Error()
+}
+
+func (w *benchWriter) close() error {
+	return w.f.Close()
+}
+
+// benchDone returns the aigers with rows in the results file `p`.
+func benchDone(p string) (map[string]bool, error) {
+	done := make(map[string]bool)
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if filepath.Ext(p) == ".json" {
+		sc := bufio.NewScanner(f)
+		sc.Buffer(nil, 1<<24)
+		for sc.Scan() {
+			var r benchRow
+			if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+				return nil, err
+			}
+			done[r.Aiger] = true
+		}
+		return done, sc.Err()
+	}
+	cr := csv.NewReader(f)
+	cr.FieldsPerRecord = -1
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rec) != 0 && rec[0] != benchFields[0] {
+			done[rec[0]] = true
+		}
+	}
+	return done, nil
+}
@@ -0,0 +1,33 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMemLimit(t *testing.T) {
+	for _, tc := range []struct {
+		gargs []string
+		rest  []string
+		mb    int64
+		err   bool
+	}{
+		{gargs: nil},
+		{gargs: []string{"-merge"}, rest: []string{"-merge"}},
+		{gargs: memLimitArgs(0)},
+		{gargs: append(memLimitArgs(4096), "-trace", "json"), rest: []string{"-trace", "json"}, mb: 4096},
+		{gargs: []string{"-memlimit=lots"}, err: true},
+	} {
+		rest, mb, err := parseMemLimit(tc.gargs)
+		if (err != nil) != tc.err {
+			t.Errorf("%v: got error %v", tc.gargs, err)
+			continue
+		}
+		if !reflect.DeepEqual(rest, tc.rest) || mb != tc.mb {
+			t.Errorf("%v: got %v %d, expected %v %d", tc.gargs, rest, mb, tc.rest, tc.mb)
+		}
+	}
+}
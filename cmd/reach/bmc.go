@@ -6,10 +6,10 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
 	"os"
 	"time"
 
-	"github.com/go-air/reach"
 	"github.com/go-air/reach/bmc"
 )
 
@@ -26,17 +26,26 @@ checking is the most effective way to find or verify the absense of corner case
 bugs which don't require very many steps of computation.  If no bugs are found,
 then the depth of the result indicates that there are no reachable bad steps
 within "depth" steps.
+
+Reachable bad states are stored as soon as they are found.  If reach receives
+SIGINT or SIGTERM, bmc stops before its next SAT call and stores the depths
+reached, without checking further aigers.  If bmc has not stopped 3 seconds
+later, or on a second signal, reach exits keeping only the results already
+stored.  After a signal, reach exits with status 128 plus the signal number:
+130 for SIGINT and 143 for SIGTERM.
 `}
 
 var bmcOpts = struct {
 	Dur      *time.Duration
 	MaxDepth *int
+	Props    *string
 }{}
 
 func initBmc(cmd *subCmd) {
 	flags := cmd.Flags
 	bmcOpts.Dur = flags.Duration("dur", 30*time.Second, "timeout")
 	bmcOpts.MaxDepth = flags.Int("to", 1<<30, "maximum depth")
+	bmcOpts.Props = flags.String("props", "", propsDoc)
 	flags.StringVar(&outDir, "o", ".", "output directory")
 	flags.Usage = func() {
 		fmt.Println(cmd.Usage)
@@ -51,36 +60,51 @@ func doBmc(cmd *subCmd, args []string) {
 	if flags.NArg() == 0 {
 		fmt.Fprintf(os.Stderr, "no aigs specified.\n")
 	}
-	for i := 0; i < flags.NArg(); i++ {
+	for i := 0; i < flags.NArg() && !interrupted(); i++ {
 		arg := flags.Arg(i)
-		if err := doBmcAiger(arg, *bmcOpts.Dur, *bmcOpts.MaxDepth); err != nil {
+		if err := doBmcAiger(cmd, arg, *bmcOpts.Dur, *bmcOpts.MaxDepth); err != nil {
 			fmt.Fprintf(os.Stderr, "error doing '%s': %s\n", arg, err)
+			emitError(cmd, arg, true, err)
 			continue
 		}
 	}
 }
 
-func doBmcAiger(fn string, dur time.Duration, to int) error {
+func doBmcAiger(cmd *subCmd, fn string, dur time.Duration, to int) error {
 	deadLine := time.Now().Add(dur)
 	aig, err := readAiger(fn)
 	if err != nil {
 		return err
 	}
-	bad := aigerBad(aig)
+	bad, err := selectBad(aigerBad(aig), *bmcOpts.Props)
+	if err != nil {
+		return err
+	}
 	if len(bad) == 0 {
 		return fmt.Errorf("ErrNoBads")
 	}
+	out, err := makeOutput(cmd, fn)
+	if err != nil {
+		return err
+	}
+	sigs := stopOnSignal()
+	defer sigs.close()
+	emit(&event{Event: "start", Cmd: cmd.Name, Aiger: fn, Output: out.RootDir(), Options: flagOptions(cmd.Flags)})
 	mc := bmc.New(aig.S, bad...)
 	mc.SetMaxDepth(to)
-	n := mc.Try(time.Until(deadLine))
-	fmt.Printf("%s: solved %d\n", fn, n)
-	out, err := reach.MakeOutput(fn, outDir)
+	mc.SetOutput(out)
+	sigs.setStop(mc.Stop)
+	n, err := mc.Try(time.Until(deadLine))
 	if err != nil {
-		return err
+		log.Printf("%s: %s", fn, err)
+		emitError(cmd, fn, true, err)
 	}
+	fmt.Printf("%s: solved %d\n", fn, n)
 	mc.FillOutput(out)
+	err = out.Store()
+	emitResults(cmd, fn, out, bad)
 	for _, b := range out.Results() {
 		fmt.Printf("\t%s\n", b)
 	}
-	return out.Store()
+	return err
 }
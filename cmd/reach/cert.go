@@ -0,0 +1,105 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-air/reach"
+)
+
+var certCmd = &subCmd{
+	Name:  "cert",
+	Flags: flag.NewFlagSet("cert", flag.ExitOnError),
+	Run:   doCert,
+	Init:  initCert,
+	Usage: "reach cert [opts] <output0> [<output1>, ...]",
+	Short: `cert exports invariants as aiger certificates.`,
+	Long: `
+cert exports the inductive invariants in reach output directories as aiger
+certificates, which can be checked by standard certificate checkers such as
+Certifaiger.  For each unreachable bad state with an invariant, a binary aiger
+<lit>-cert.aig is written to the output directory.
+
+The certificate is a witness circuit with the inputs and latches of the aiger
+in the output directory, in the same order, and a single bad state literal
+which is the disjunction of the original bad state literal and the negation of
+the invariant.  Its property is inductive by itself.
+
+Each certificate is read back and checked against the aiger.  If any
+certificate fails to be written or verified, reach exits with status 1.
+`}
+
+var certOpts = struct {
+	Dur *time.Duration
+}{}
+
+func initCert(cmd *subCmd) {
+	flags := cmd.Flags
+	certOpts.Dur = flags.Duration("dur", 5*time.Second, "time limit for checking each certificate.")
+	flags.Usage = func() {
+		fmt.Println(cmd.Usage)
+		flags.PrintDefaults()
+		fmt.Println(cmd.Long)
+	}
+}
+
+func doCert(cmd *subCmd, args []string) {
+	flags := cmd.Flags
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "no output directories specified.\n")
+		return
+	}
+	hasErr := false
+	for _, arg := range flags.Args() {
+		if !doCertOutput(arg) {
+			hasErr = true
+		}
+	}
+	if hasErr {
+		os.Exit(1)
+	}
+}
+
+func doCertOutput(arg string) bool {
+	out, err := reach.OpenOutput(arg)
+	if err != nil {
+		fmt.Printf("error opening '%s': %s\n", arg, err)
+		return false
+	}
+	defer out.Close()
+	ok := true
+	for i, bad := range out.Results() {
+		if !bad.IsSolved() || bad.IsReachable() {
+			continue
+		}
+		if _, err := os.Stat(out.InvariantPath(i)); err != nil && len(bad.Invariant) == 0 {
+			fmt.Printf("\t%s: no invariant\n", bad)
+			continue
+		}
+		if err := out.StoreCertificate(i); err != nil {
+			fmt.Printf("\terror writing certificate for %s: %s\n", bad, err)
+			ok = false
+			continue
+		}
+		if errs := out.TryVerifyResult(i, *certOpts.Dur); len(errs) != 0 {
+			for _, e := range errs {
+				fmt.Printf("\terror verifying %s: %s\n", bad, e)
+			}
+			ok = false
+			continue
+		}
+		p := out.CertificatePath(i)
+		if out.Archive() != "" {
+			p = out.Archive() + ":" + filepath.Base(p)
+		}
+		fmt.Printf("\twrote verified certificate for %s to %s\n", bad, p)
+	}
+	return ok
+}
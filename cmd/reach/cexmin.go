@@ -0,0 +1,92 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-air/reach"
+)
+
+var cexminCmd = &subCmd{
+	Name:  "cexmin",
+	Flags: flag.NewFlagSet("cexmin", flag.ExitOnError),
+	Run:   doCexmin,
+	Init:  initCexmin,
+	Usage: "reach cexmin [opts] <output0> [<output1>, ...]",
+	Short: `cexmin minimizes counterexample traces in output directories.`,
+	Long: `
+cexmin minimizes the counterexample traces in reach output directories.  The
+trace is cut after the first state in which the bad state is reached, and
+then inputs, as well as initial values of latches without a fixed initial
+value, are set to false whenever the bad state is still reached, which may
+shorten the trace further.
+
+The smaller trace is verified and, if verification succeeds, replaces the
+original trace in the same format, and the depth of the result is set to its
+length.  The length and the number of true inputs of the trace before and
+after are reported.  If any trace fails to be minimized or verified, reach
+exits with status 1 and the original trace is kept.  If minimizing a trace
+takes longer than -dur, the partially minimized trace is verified and kept
+as above, with a warning.
+`}
+
+var cexminOpts = struct {
+	Dur *time.Duration
+}{}
+
+func initCexmin(cmd *subCmd) {
+	flags := cmd.Flags
+	cexminOpts.Dur = flags.Duration("dur", 30*time.Second, "time limit for minimizing each trace.")
+	flags.Usage = func() {
+		fmt.Println(cmd.Usage)
+		flags.PrintDefaults()
+		fmt.Println(cmd.Long)
+	}
+}
+
+func doCexmin(cmd *subCmd, args []string) {
+	flags := cmd.Flags
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "no output directories specified.\n")
+		return
+	}
+	hasErr := false
+	for _, arg := range flags.Args() {
+		out, err := reach.OpenOutput(arg)
+		if err != nil {
+			fmt.Printf("error opening '%s': %s\n", arg, err)
+			hasErr = true
+			continue
+		}
+		for i, bad := range out.Results() {
+			if !bad.IsReachable() || !out.IsVerifiable(i) {
+				continue
+			}
+			before, after, errs := out.MinimizeTrace(i, *cexminOpts.Dur)
+			if len(errs) == 1 && errors.Is(errs[0], reach.ErrTimeout) {
+				fmt.Printf("\twarning minimizing %s: %s\n", bad, errs[0])
+				fmt.Printf("\tpartially minimized %s: %s -> %s\n", bad, before, after)
+				continue
+			}
+			if len(errs) != 0 {
+				for _, e := range errs {
+					fmt.Printf("\terror minimizing %s: %s\n", bad, e)
+				}
+				hasErr = true
+				continue
+			}
+			fmt.Printf("\tminimized %s: %s -> %s\n", bad, before, after)
+		}
+		out.Close()
+	}
+	if hasErr {
+		os.Exit(1)
+	}
+}
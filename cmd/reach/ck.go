@@ -6,10 +6,16 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"text/tabwriter"
 	"time"
 
+	"github.com/go-air/gini/logic/aiger"
 	"github.com/go-air/reach"
 )
 
@@ -18,24 +24,55 @@ var ckCmd = &subCmd{
 	Flags: flag.NewFlagSet("ck", flag.ExitOnError),
 	Run:   doCk,
 	Init:  initCk,
-	Usage: "reach ck [opts] <output0> [<output1>, ...]",
+	Usage: "reach ck [opts] <output0> [<output1>, ...]\n       reach ck [opts] -aig <aiger> (-witness <file> | -cert <file>)",
 	Short: `ck checks traces and inductive invariants.`,
 	Long: `
 ck verifies traces and inductive invariants in reach output directories.  
 ck prints out whether or each bad state is verified and any errors.  If 
 there are any bad states which fail verification, then check causes reach 
 to exit with status 1. Otherwise, reach exits with status 0.
+
+Results are verified concurrently by -j workers.  When there are fewer
+results than workers, the consecution checks of large invariants are split
+among the remaining workers.  After the results, ck prints a summary table
+giving the outcome and verification time of each result.
+
+Output directories with a manifest are first checked for missing or corrupted
+files, which cause ck to fail.
+
+With -aig, ck instead verifies results of other tools against an aiger.
+-witness gives an aiger 1.9 (HWMCC) witness file, whose counterexample
+trace is simulated and checked.  -cert gives an inductive invariant showing
+the bad state with index -prop is unreachable, either as dimacs cnf over
+the variables of the aiger as read by reach (.cnf), as a combinational
+aiger (.aig) with one input per latch and a single output, or as a witness
+circuit (.aig) with the inputs and latches of the aiger, as written by
+"reach cert".
+
+In this mode ck uses HWMCC style exit codes: 10 for a verified
+counterexample, 20 for a verified invariant, 0 if the witness gives
+no counterexample, and 1 if verification fails.
 `}
 
 var ckOpts = struct {
 	Verbose *bool
 	Dur     *time.Duration
+	Aig     *string
+	Witness *string
+	Cert    *string
+	Prop    *int
+	J       *int
 }{}
 
 func initCk(cmd *subCmd) {
 	flags := cmd.Flags
 	ckOpts.Verbose = flags.Bool("v", false, "verbose, provide more info.")
 	ckOpts.Dur = flags.Duration("dur", 5*time.Second, "time limit for checking each invariant.")
+	ckOpts.Aig = flags.String("aig", "", "aiger against which to check -witness or -cert.")
+	ckOpts.Witness = flags.String("witness", "", "aiger 1.9 witness to check.")
+	ckOpts.Cert = flags.String("cert", "", "invariant certificate (.cnf or .aig) to check.")
+	ckOpts.Prop = flags.Int("prop", 0, "index of the bad state for -cert.")
+	ckOpts.J = flags.Int("j", 0, "number of concurrent verification workers (default number of CPUs).")
 	flags.Usage = func() {
 		fmt.Println(cmd.Usage)
 		flags.PrintDefaults()
@@ -46,35 +83,269 @@ func initCk(cmd *subCmd) {
 func doCk(cmd *subCmd, args []string) {
 	flags := cmd.Flags
 	flags.Parse(args)
+	if *ckOpts.Aig != "" {
+		code := doCkExternal()
+		emitCkExternal(cmd, code)
+		os.Exit(code)
+	}
 	if flags.NArg() == 0 {
 		fmt.Fprintf(os.Stderr, "no output directories specified.\n")
 	}
 	hasErr := false
+	var outs []*reach.Output
+	var tasks []*ckTask
 	for _, arg := range flags.Args() {
 		out, err := reach.OpenOutput(arg)
 		if err != nil {
 			fmt.Printf("error opening '%s': %s\n", arg, err)
+			emitError(cmd, arg, false, err)
 			hasErr = true
 			continue
 		}
-		log.Printf("check %s:\n", arg)
+		emit(&event{Event: "start", Cmd: cmd.Name, Output: arg})
+		outs = append(outs, out)
 		for i, bad := range out.Results() {
-			if !out.IsVerifiable(i) {
-				fmt.Printf("\t%s: nothing to check\n", bad)
-				continue
-			}
-			if errs := out.TryVerifyResult(i, *ckOpts.Dur); len(errs) != 0 {
-				hasErr = true
-				for _, e := range errs {
-					fmt.Printf("\terror verifying %s: %s\n", bad, e)
-				}
-				hasErr = true
-			} else {
-				fmt.Printf("\tverified %s\n", bad)
+			tasks = append(tasks, &ckTask{dir: arg, out: out, i: i, bad: bad, limit: *ckOpts.Dur})
+		}
+	}
+	j := numJobs(*ckOpts.J)
+	// with fewer results than workers, split the invariant checks.
+	if len(tasks) != 0 && j/len(tasks) > 1 {
+		for _, out := range outs {
+			out.SetCheckWorkers(j / len(tasks))
+		}
+	}
+	ckRun(tasks, j)
+	dir := ""
+	for _, t := range tasks {
+		if t.dir != dir {
+			dir = t.dir
+			log.Printf("check %s:\n", dir)
+		}
+		switch {
+		case !t.checked:
+			fmt.Printf("\t%s: nothing to check\n", t.bad)
+		case len(t.errs) != 0:
+			hasErr = true
+			for _, e := range t.errs {
+				fmt.Printf("\terror verifying %s: %s\n", t.bad, e)
 			}
+		default:
+			fmt.Printf("\tverified %s\n", t.bad)
 		}
+		t.emit(cmd)
+	}
+	if len(tasks) != 0 {
+		ckSummary(os.Stdout, tasks)
+	}
+	for _, out := range outs {
+		out.Close()
 	}
 	if hasErr {
 		os.Exit(1)
 	}
 }
+
+// ckTask is the verification of one result in an output directory.
+type ckTask struct {
+	dir     string
+	out     *reach.Output
+	i       int
+	bad     *reach.Result
+	limit   time.Duration // for checking an invariant.
+	checked bool
+	errs    []error
+	dur     time.Duration
+}
+
+func (t *ckTask) run() {
+	if !t.out.IsVerifiable(t.i) {
+		return
+	}
+	start := time.Now()
+	t.checked = true
+	t.errs = t.out.TryVerifyResult(t.i, t.limit)
+	t.dur = time.Since(start)
+}
+
+// status gives the outcome of `t` for summaries: "ok", "FAIL" or "none" if
+// there was nothing to check.
+func (t *ckTask) status() string {
+	switch {
+	case !t.checked:
+		return "none"
+	case len(t.errs) != 0:
+		return "FAIL"
+	}
+	return "ok"
+}
+
+// emit emits the verification event of `t`.
+func (t *ckTask) emit(cmd *subCmd) {
+	e := &event{Event: "verification", Cmd: cmd.Name, Output: t.dir, Result: t.bad, Check: t.status(), Dur: t.dur}
+	for _, err := range t.errs {
+		e.Errors = append(e.Errors, err.Error())
+	}
+	emit(e)
+}
+
+// numJobs returns the number of concurrent jobs or workers given by a -j
+// flag, which is the number of CPUs if `j` is not positive.
+func numJobs(j int) int {
+	if j < 1 {
+		return runtime.NumCPU()
+	}
+	return j
+}
+
+// ckRun runs `tasks` with `j` workers.
+func ckRun(tasks []*ckTask, j int) {
+	ch := make(chan *ckTask)
+	var wg sync.WaitGroup
+	for w := 0; w < j; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range ch {
+				t.run()
+			}
+		}()
+	}
+	for _, t := range tasks {
+		ch <- t
+	}
+	close(ch)
+	wg.Wait()
+}
+
+func ckSummary(w io.Writer, tasks []*ckTask) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "output\tbad\tstatus\tcheck\ttime\n")
+	for _, t := range tasks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", t.dir, t.bad.M, t.bad.FormatStatus(), t.status(), t.dur)
+	}
+	tw.Flush()
+}
+
+// exit codes for checking external results.
+const (
+	ckExitFail    = 1
+	ckExitUnknown = 0
+	ckExitSat     = 10
+	ckExitUnsat   = 20
+)
+
+// emitCkExternal emits the verification event of checking a witness or
+// certificate with exit code `code`.
+func emitCkExternal(cmd *subCmd, code int) {
+	e := &event{Event: "verification", Cmd: cmd.Name, Aiger: *ckOpts.Aig, File: *ckOpts.Witness}
+	if e.File == "" {
+		e.File = *ckOpts.Cert
+	}
+	switch code {
+	case ckExitSat, ckExitUnsat:
+		e.Check = "ok"
+	case ckExitUnknown:
+		e.Check = "none"
+	default:
+		e.Check = "FAIL"
+	}
+	emit(e)
+}
+
+func doCkExternal() int {
+	if (*ckOpts.Witness == "") == (*ckOpts.Cert == "") {
+		fmt.Fprintf(os.Stderr, "need exactly one of -witness or -cert with -aig.\n")
+		return ckExitFail
+	}
+	aig, err := readAiger(*ckOpts.Aig)
+	if err != nil {
+		log.Printf("error reading '%s': %s", *ckOpts.Aig, err)
+		return ckExitFail
+	}
+	if *ckOpts.Witness != "" {
+		return ckWitness(aig, *ckOpts.Witness)
+	}
+	return ckCert(aig, *ckOpts.Cert, *ckOpts.Prop)
+}
+
+func ckWitness(aig *aiger.T, fn string) int {
+	f, err := os.Open(fn)
+	if err != nil {
+		log.Printf("error opening witness: %s", err)
+		return ckExitFail
+	}
+	defer f.Close()
+	wit, err := reach.DecodeAigerWitness(f, aig)
+	if err != nil {
+		log.Printf("error reading witness '%s': %s", fn, err)
+		return ckExitFail
+	}
+	if wit.Status != 1 {
+		fmt.Printf("%s: no counterexample to check\n", fn)
+		return ckExitUnknown
+	}
+	if errs := wit.Trace.Verify(aig.Sys()); len(errs) != 0 {
+		for _, e := range errs {
+			fmt.Printf("\terror verifying witness %s: %s\n", fn, e)
+		}
+		return ckExitFail
+	}
+	fmt.Printf("\tverified witness %s for %v\n", fn, wit.Props)
+	return ckExitSat
+}
+
+func ckCert(aig *aiger.T, fn string, prop int) int {
+	bads := aigerBad(aig)
+	if prop < 0 || prop >= len(bads) {
+		fmt.Fprintf(os.Stderr, "no bad state with index %d.\n", prop)
+		return ckExitFail
+	}
+	trans := aig.Sys()
+	var inv reach.Invariant
+	switch filepath.Ext(fn) {
+	case ".cnf":
+		f, err := os.Open(fn)
+		if err != nil {
+			log.Printf("error opening certificate: %s", err)
+			return ckExitFail
+		}
+		defer f.Close()
+		inv, err = reach.ReadDimacsInvariant(f)
+		if err != nil {
+			log.Printf("error reading certificate '%s': %s", fn, err)
+			return ckExitFail
+		}
+	default:
+		g, err := readAiger(fn)
+		if err != nil {
+			log.Printf("error reading certificate '%s': %s", fn, err)
+			return ckExitFail
+		}
+		if len(g.Latches) != 0 {
+			return ckErrs(fn, prop, reach.CheckAigerCertificate(trans, bads[prop], g, *ckOpts.Dur))
+		}
+		m, err := reach.AigerInvariant(trans, g)
+		if err != nil {
+			log.Printf("error in certificate '%s': %s", fn, err)
+			return ckExitFail
+		}
+		inv = reach.Invariant{m, 0}
+	}
+	var errs []error
+	for _, f := range inv.Check(trans, bads[prop], *ckOpts.Dur) {
+		errs = append(errs, f)
+	}
+	return ckErrs(fn, prop, errs)
+}
+
+func ckErrs(fn string, prop int, errs []error) int {
+	if len(errs) != 0 {
+		for _, e := range errs {
+			fmt.Printf("\terror verifying certificate %s: %s\n", fn, e)
+		}
+		return ckExitFail
+	}
+	fmt.Printf("\tverified certificate %s for b%d\n", fn, prop)
+	return ckExitUnsat
+}
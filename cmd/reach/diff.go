@@ -0,0 +1,228 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/go-air/reach"
+)
+
+var diffCmd = &subCmd{
+	Name:  "diff",
+	Flags: flag.NewFlagSet("diff", flag.ExitOnError),
+	Run:   doDiff,
+	Init:  initDiff,
+	Usage: "reach diff [opts] <outA> <outB>",
+	Short: `diff compares the results of two runs.`,
+	Long: `
+diff compares the results in two output directories or archives, or two trees
+of them.  Results are matched by the sha256 hash of their aiger and their bad
+state, so outputs of the same model may be compared wherever they are stored.
+
+For each matched result, diff prints changes in status, depth, trace length
+and invariant size (clauses and literals).  Results present in only one of
+<outA> and <outB> are listed with -v.  A result which is reachable in one
+and unreachable in the other is a contradiction, which is printed as an
+error since one of the two must be wrong.
+
+If a tree holds several results for the same aiger hash and bad state, the
+first one found is compared.  Later ones which contradict it are printed as
+contradictions too, and the others are listed with -v.
+
+diff exits with status 1 if there are any contradictions, with status 2 if
+an output cannot be read, and with status 0 otherwise.
+`}
+
+var diffOpts = struct {
+	Verbose *bool
+}{}
+
+func initDiff(cmd *subCmd) {
+	flags := cmd.Flags
+	diffOpts.Verbose = flags.Bool("v", false, "verbose, list unmatched results.")
+	flags.Usage = func() {
+		fmt.Println(cmd.Usage)
+		flags.PrintDefaults()
+		fmt.Println(cmd.Long)
+	}
+}
+
+// diffEntry is a result of an output with its evidence sizes.
+type diffEntry struct {
+	path   string
+	res    *reach.Result
+	traceN int                  // trace length, -1 if none.
+	inv    *reach.InvariantSize // nil if none.
+}
+
+// diffDup is a result found again in the same tree, with the first one.
+type diffDup struct {
+	first, dup *diffEntry
+}
+
+func doDiff(cmd *subCmd, args []string) {
+	flags := cmd.Flags
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.SetOutput(os.Stderr)
+		flags.Usage()
+		os.Exit(2)
+	}
+	as, aKeys, aDups, err := diffEntries(flags.Arg(0))
+	if err != nil {
+		log.Printf("error reading '%s': %s", flags.Arg(0), err)
+		os.Exit(2)
+	}
+	bs, bKeys, bDups, err := diffEntries(flags.Arg(1))
+	if err != nil {
+		log.Printf("error reading '%s': %s", flags.Arg(1), err)
+		os.Exit(2)
+	}
+	nMatch, nChanged, nContra := 0, 0, 0
+	for _, d := range append(aDups, bDups...) {
+		if _, contra := diffResults(d.first, d.dup); contra {
+			nContra++
+			fmt.Printf("error: %s bad[%s]: contradiction: %s, but %s in %s\n",
+				d.dup.path, d.dup.res.M, d.dup.res.FormatStatus(), d.first.res.FormatStatus(), d.first.path)
+		} else if *diffOpts.Verbose {
+			fmt.Printf("%s %s: duplicate of %s\n", d.dup.path, d.dup.res, d.first.path)
+		}
+	}
+	for _, k := range aKeys {
+		a := as[k]
+		b, ok := bs[k]
+		if !ok {
+			if *diffOpts.Verbose {
+				fmt.Printf("%s %s: only in A\n", a.path, a.res)
+			}
+			continue
+		}
+		nMatch++
+		ds, contra := diffResults(a, b)
+		if contra {
+			nContra++
+			fmt.Printf("error: %s bad[%s]: contradiction: %s in A, %s in B\n",
+				a.path, a.res.M, a.res.FormatStatus(), b.res.FormatStatus())
+			continue
+		}
+		if len(ds) != 0 {
+			nChanged++
+		}
+		for _, d := range ds {
+			fmt.Printf("%s bad[%s]: %s\n", a.path, a.res.M, d)
+		}
+	}
+	if *diffOpts.Verbose {
+		for _, k := range bKeys {
+			if _, ok := as[k]; !ok {
+				fmt.Printf("%s %s: only in B\n", bs[k].path, bs[k].res)
+			}
+		}
+	}
+	fmt.Printf("%d matched results, %d changed, %d contradictions, %d only in A, %d only in B\n",
+		nMatch, nChanged, nContra, len(aKeys)-nMatch, len(bKeys)-nMatch)
+	if nContra != 0 {
+		os.Exit(1)
+	}
+}
+
+// diffEntries reads the results of the outputs under `root`, by aiger hash
+// and bad state.  The keys are returned in the order found.  Results with a
+// key found before are not entered, but returned as duplicates.
+func diffEntries(root string) (map[string]*diffEntry, []string, []diffDup, error) {
+	ps, err := findOutputs(root)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(ps) == 0 {
+		return nil, nil, nil, fmt.Errorf("no outputs found")
+	}
+	res := make(map[string]*diffEntry)
+	var keys []string
+	var dups []diffDup
+	for _, p := range ps {
+		out, err := reach.OpenOutput(p)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		h, err := out.AigerHash()
+		if err != nil {
+			out.Close()
+			return nil, nil, nil, err
+		}
+		for i, bad := range out.Results() {
+			k := fmt.Sprintf("%s/%d", h, bad.M)
+			e := newDiffEntry(p, out, i)
+			if first, ok := res[k]; ok {
+				dups = append(dups, diffDup{first: first, dup: e})
+				continue
+			}
+			keys = append(keys, k)
+			res[k] = e
+		}
+		out.Close()
+	}
+	return res, keys, dups, nil
+}
+
+func newDiffEntry(p string, out *reach.Output, i int) *diffEntry {
+	bad := out.Results()[i]
+	e := &diffEntry{path: p, res: bad, traceN: -1}
+	switch {
+	case bad.IsReachable():
+		if tr, err := out.Trace(i); err == nil {
+			e.traceN = tr.Len()
+		}
+	case bad.IsUnreachable():
+		var inv reach.Invariant
+		if err := out.Invariant(&inv, i); err == nil {
+			sz := inv.Size()
+			e.inv = &sz
+		}
+	}
+	return e
+}
+
+// diffResults returns the differences between `a` and `b` and whether they
+// contradict each other.
+func diffResults(a, b *diffEntry) ([]string, bool) {
+	ra, rb := a.res, b.res
+	if ra.IsSolved() && rb.IsSolved() && ra.Status != rb.Status {
+		return nil, true
+	}
+	var ds []string
+	if ra.Status != rb.Status {
+		ds = append(ds, fmt.Sprintf("status %s -> %s", ra.FormatStatus(), rb.FormatStatus()))
+	}
+	if ra.Depth != rb.Depth {
+		ds = append(ds, fmt.Sprintf("depth %d -> %d", ra.Depth, rb.Depth))
+	}
+	if a.traceN != b.traceN {
+		ds = append(ds, fmt.Sprintf("trace length %s -> %s", diffLen(a.traceN), diffLen(b.traceN)))
+	}
+	switch {
+	case a.inv == nil && b.inv == nil:
+	case a.inv == nil || b.inv == nil || *a.inv != *b.inv:
+		ds = append(ds, fmt.Sprintf("invariant %s -> %s", diffInv(a.inv), diffInv(b.inv)))
+	}
+	return ds, false
+}
+
+func diffLen(n int) string {
+	if n < 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+func diffInv(sz *reach.InvariantSize) string {
+	if sz == nil {
+		return "none"
+	}
+	return sz.String()
+}
@@ -0,0 +1,47 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-air/reach"
+)
+
+func TestDiffDuplicates(t *testing.T) {
+	fn := testAiger(t)
+	tree := t.TempDir()
+	for _, d := range []string{"a", "b"} {
+		if _, stderr, code := runReach(t, "bmc", "-o", filepath.Join(tree, d), fn); code != 0 {
+			t.Fatalf("bmc exited with %d:\n%s", code, stderr)
+		}
+	}
+	stdout, stderr, code := runReach(t, "diff", "-v", tree, filepath.Join(tree, "a"))
+	if code != 0 {
+		t.Fatalf("diff exited with %d:\n%s\n%s", code, stdout, stderr)
+	}
+	if !strings.Contains(stdout, "duplicate of") {
+		t.Errorf("duplicate not listed:\n%s", stdout)
+	}
+	// a third output claiming the bad state is unreachable.
+	a, err := reach.OpenOutput(filepath.Join(tree, "a", "follow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := a.Results()[0].M
+	a.Close()
+	c, err := reach.MakeOutput(fn, filepath.Join(tree, "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.StoreResult(&reach.Result{M: m, Status: -1}); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, code = runReach(t, "diff", tree, filepath.Join(tree, "a"))
+	if code != 1 || !strings.Contains(stdout, "contradiction") {
+		t.Errorf("diff exited with %d, expected 1 with a contradiction:\n%s", code, stdout)
+	}
+}
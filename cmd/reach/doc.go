@@ -12,24 +12,79 @@
 //  	bmc	bmc performs SAT based bounded model checking.
 //  	sim	sim simulates aiger.
 //  	ck	ck checks traces and inductive invariants.
+//  	cert	cert exports invariants as aiger certificates.
+//  	invmin	invmin minimizes inductive invariants in output directories.
+//  	cexmin	cexmin minimizes counterexample traces in output directories.
 //  	stim	stim outputs an aiger stimulus from an output directory.
-//  	aag	aag outputs an ascii aiger of the reach internal representation from an output directory.
-//  	aig	aig outputs an binary aiger of the Reach internal representation of an aiger.
+//  	aag	aag outputs an ascii aiger of the Reach internal aig.
+//  	aig	aig outputs an binary aiger of the Reach internal aig.
+//  	vcd	vcd outputs value change dump waveforms of traces in an output directory.
+//  	pack	pack archives output directories as single files.
+//  	report	report tabulates the results in trees of output directories.
+//  	diff	diff compares the results of two runs.
+//  	bench	bench runs an engine over a directory of aigers.
+//  	tune	tune searches for the best iic options on a set of aigers.
+//  	run	run runs the engines and post-steps of a job file.
+//  	hwmcc	hwmcc checks an aiger with a portfolio of engines, HWMCC style.
 //  	info	info provides summary information about an aiger or output.
 //
 //  global options:
 //    -cpuprof string
 //      	file to output cpu profile
+//    -json
+//      	print newline delimited json events on stdout
+//    -merge
+//      	merge results into existing output directories
+//    -trace string
+//      	format of stored traces (bin, json, text) (default "bin")
+//
+//  With -json, iic, bmc, sim, ck, info, stim, aag and aig print one json object
+//  per line on stdout for each event, and their other messages on stderr.  The
+//  field Event is one of
+//
+//  	start         a command starts on an aiger or output.
+//  	progress      an engine proceeds: iic starts a bad state, sim has
+//  	              taken Steps steps.
+//  	result        a result is known or read: Result holds the result as
+//  	              stored in outputs, Aig the sizes of an aiger for info, File
+//  	              the file written by stim, aag or aig, or Data the contents
+//  	              they would write to stdout, base64 encoded.
+//  	verification  ck has verified a result: Check is ok, FAIL or none, with
+//  	              Errors and the duration Dur.
+//  	error         a command failed, as described by Error.
+//
+//  Events also have the fields Time, Cmd, and Aiger, Output and File where they
+//  apply.  Durations are in nanoseconds.  Other commands print no events, and
+//  -json does not change their output.
 //
 //  For help on a command, try "reach <cmd> -h".
 //  ⎣ ⇨ reach iic -h
 //  reach iic [options] <aiger0> [<aiger1>, ...]
+//    -csift
+//      	do consecutive sifting. (default true)
+//    -deep
+//      	keep deep proof obligations. (default true)
 //    -dur duration
-//      	timeout (default 30s)
+//      	timeout. (default 30s)
+//    -filter
+//      	filter proof obligations. (default true)
+//    -justify
+//      	justify proof obligations. (default true)
 //    -o string
 //      	output directory (default ".")
+//    -opts string
+//      	json options file, as written by "reach tune".
+//    -pp
+//      	pre-process aig. (default true)
+//    -props string
+//      	comma separated indices of the bad states to check (default all).
+//    -pull
+//      	do pulling with consecutive sifting. (default true)
+//    -rmlits
+//      	remove literals when generalizing.
 //    -to int
-//      	maximum depth (default 1073741824)
+//      	maximum depth. (default 1073741824)
+//    -v	run with verbosity.
 //
 //  iic runs an incremental inductive checker on the supplied aiger files to find
 //  or disprove reachability of bad states. Iic can find and output deep
@@ -40,12 +95,29 @@
 //  depths for traces are the trace length itself.  For unknown results, depths
 //  represent the depth to which it is known no counterexample trace exists.
 //
+//  The result for each bad state is stored as soon as it is found.  If reach
+//  receives SIGINT or SIGTERM, iic stops between SAT calls and stores the
+//  depth reached for the current bad state as an unknown result, without
+//  checking further bad states or aigers.  The clauses of the frame at the
+//  depth reached are stored with the unknown result in "<lit>-frames.cnf", in
+//  dimacs format, and listed in the manifest; they are not an invariant and
+//  are not verified.  If iic has not stopped 3 seconds later, or on a second
+//  signal, reach exits keeping only the results already stored.  After a
+//  signal, reach exits with status 128 plus the signal number: 130 for SIGINT
+//  and 143 for SIGTERM.
+//
+//  -opts reads the options from a json file of iic.Options, such as the best
+//  configuration found by "reach tune".  Flags given explicitly override the
+//  options in the file, except -dur, which always sets the time limit.
+//
 //  ⎣ ⇨ reach bmc -h
 //  reach bmc [opts] <aiger0> <aiger1> ...
 //    -dur duration
 //      	timeout (default 30s)
 //    -o string
 //      	output directory (default ".")
+//    -props string
+//      	comma separated indices of the bad states to check (default all).
 //    -to int
 //      	maximum depth (default 1073741824)
 //
@@ -55,17 +127,37 @@
 //  then the depth of the result indicates that there are no reachable bad steps
 //  within "depth" steps.
 //
+//  Reachable bad states are stored as soon as they are found.  If reach receives
+//  SIGINT or SIGTERM, bmc stops before its next SAT call and stores the depths
+//  reached, without checking further aigers.  If bmc has not stopped 3 seconds
+//  later, or on a second signal, reach exits keeping only the results already
+//  stored.  After a signal, reach exits with status 128 plus the signal number:
+//  130 for SIGINT and 143 for SIGTERM.
+//
 //  ⎣ ⇨ reach sim -h
 //  reach sim [opts] <aiger>
 //    -dur duration
-//      	timeout (default 30s)
+//      	timeout. (default 30s)
+//    -n int
+//      	repeat n times until stopping condition. (default 1)
 //    -o string
 //      	output directory (default ".")
+//    -opts string
+//      	json options file of sim.Options.
+//    -props string
+//      	comma separated indices of the bad states to check (default all).
+//    -restart int
+//      	restart factor for Luby series restarts (default 0).
+//    -seed int
+//      	random seed. (default 44)
 //    -to int
-//      	maximum depth (default 1073741824)
+//      	stop after reaching the specified depth (if -restart==0). (default 1073741824)
+//    -trace
+//      	generate traces. (default true)
 //    -until int
 //      	"-until n" will limit sim so that it runs at most
 //      	until all bad states have been reached n times. (default 1)
+//    -v	verbosity.
 //    -win int
 //      	memory for trace gen in steps. (default 1024)
 //
@@ -79,25 +171,125 @@
 //  Reachable bad states have 'Depth' reported as the true number of steps, which
 //  may exceed the trace memory limit.
 //
+//  Reached bad states are stored as soon as they are first reached.  If reach
+//  receives SIGINT or SIGTERM, sim stops after the current step and stores its
+//  results, without simulating further aigers.  On a second signal, reach exits
+//  keeping only the results already stored.  After a signal, reach exits with
+//  status 128 plus the signal number: 130 for SIGINT and 143 for SIGTERM.
+//
+//  -opts reads the options from a json file of sim.Options.  Flags given
+//  explicitly override the options in the file.
+//
 //  ⎣ ⇨ reach ck -h
 //  reach ck [opts] <output0> [<output1>, ...]
+//         reach ck [opts] -aig <aiger> (-witness <file> | -cert <file>)
+//    -aig string
+//      	aiger against which to check -witness or -cert.
+//    -cert string
+//      	invariant certificate (.cnf or .aig) to check.
 //    -dur duration
 //      	time limit for checking each invariant. (default 5s)
+//    -j int
+//      	number of concurrent verification workers (default number of CPUs).
+//    -prop int
+//      	index of the bad state for -cert.
 //    -v	verbose, provide more info.
+//    -witness string
+//      	aiger 1.9 witness to check.
 //
 //  ck verifies traces and inductive invariants in reach output directories.
 //  ck prints out whether or each bad state is verified and any errors.  If
 //  there are any bad states which fail verification, then check causes reach
 //  to exit with status 1. Otherwise, reach exits with status 0.
 //
+//  Results are verified concurrently by -j workers.  When there are fewer
+//  results than workers, the consecution checks of large invariants are split
+//  among the remaining workers.  After the results, ck prints a summary table
+//  giving the outcome and verification time of each result.
+//
+//  Output directories with a manifest are first checked for missing or corrupted
+//  files, which cause ck to fail.
+//
+//  With -aig, ck instead verifies results of other tools against an aiger.
+//  -witness gives an aiger 1.9 (HWMCC) witness file, whose counterexample
+//  trace is simulated and checked.  -cert gives an inductive invariant showing
+//  the bad state with index -prop is unreachable, either as dimacs cnf over
+//  the variables of the aiger as read by reach (.cnf), as a combinational
+//  aiger (.aig) with one input per latch and a single output, or as a witness
+//  circuit (.aig) with the inputs and latches of the aiger, as written by
+//  "reach cert".
+//
+//  In this mode ck uses HWMCC style exit codes: 10 for a verified
+//  counterexample, 20 for a verified invariant, 0 if the witness gives
+//  no counterexample, and 1 if verification fails.
+//
+//  ⎣ ⇨ reach cert -h
+//  reach cert [opts] <output0> [<output1>, ...]
+//    -dur duration
+//      	time limit for checking each certificate. (default 5s)
+//
+//  cert exports the inductive invariants in reach output directories as aiger
+//  certificates, which can be checked by standard certificate checkers such as
+//  Certifaiger.  For each unreachable bad state with an invariant, a binary aiger
+//  <lit>-cert.aig is written to the output directory.
+//
+//  The certificate is a witness circuit with the inputs and latches of the aiger
+//  in the output directory, in the same order, and a single bad state literal
+//  which is the disjunction of the original bad state literal and the negation of
+//  the invariant.  Its property is inductive by itself.
+//
+//  Each certificate is read back and checked against the aiger.  If any
+//  certificate fails to be written or verified, reach exits with status 1.
+//
+//  ⎣ ⇨ reach invmin -h
+//  reach invmin [opts] <output0> [<output1>, ...]
+//    -dur duration
+//      	time limit for minimizing each invariant. (default 30s)
+//
+//  invmin minimizes the inductive invariants in reach output directories.  Clauses
+//  which are not needed for the invariant to be inductive and to exclude the bad
+//  state are removed, and then literals are removed from the remaining clauses.
+//
+//  The smaller invariant is verified and, if verification succeeds, replaces the
+//  original invariant, as well as the aiger certificate if one has been written
+//  by "reach cert".  The sizes of the invariant before and after are reported.
+//  If any invariant fails to be minimized or verified, reach exits with status 1
+//  and the original invariant is kept.  If minimizing an invariant takes longer
+//  than -dur, the partially minimized invariant is verified and kept as above,
+//  with a warning.
+//
+//  ⎣ ⇨ reach cexmin -h
+//  reach cexmin [opts] <output0> [<output1>, ...]
+//    -dur duration
+//      	time limit for minimizing each trace. (default 30s)
+//
+//  cexmin minimizes the counterexample traces in reach output directories.  The
+//  trace is cut after the first state in which the bad state is reached, and
+//  then inputs, as well as initial values of latches without a fixed initial
+//  value, are set to false whenever the bad state is still reached, which may
+//  shorten the trace further.
+//
+//  The smaller trace is verified and, if verification succeeds, replaces the
+//  original trace in the same format, and the depth of the result is set to its
+//  length.  The length and the number of true inputs of the trace before and
+//  after are reported.  If any trace fails to be minimized or verified, reach
+//  exits with status 1 and the original trace is kept.  If minimizing a trace
+//  takes longer than -dur, the partially minimized trace is verified and kept
+//  as above, with a warning.
+//
 //  ⎣ ⇨ reach stim -h
 //  reach stim [opts] <output>
 //    -o string
 //      	suffix (after bad.) for aiger stimuli output files.
+//    -witness
+//      	output aiger 1.9 witnesses.
 //
 //  stim output saiger stimuli from an output directory.  The output
 //  directory should have a .trace file associated with a bad state.
 //
+//  With -witness, stim outputs aiger 1.9 (HWMCC) witnesses, which include
+//  the property and the initial latch values, instead of stimuli.
+//
 //  By default, the output is written to stdout.
 //
 //  ⎣ ⇨ reach aag -h
@@ -120,10 +312,290 @@
 //
 //  By default, the output is written to stdout.
 //
+//  ⎣ ⇨ reach vcd -h
+//  reach vcd [opts] <output>
+//    -gates
+//      	dump all AND gates.
+//    -o string
+//      	output directory for vcd files.
+//
+//  vcd outputs the traces in an output directory as value change dump (vcd) files,
+//  which can be viewed with waveform viewers such as GTKWave.  Inputs, latches and
+//  watches are dumped, named by the aiger symbol table where available.  With
+//  -gates, all AND gates are recomputed from the aiger and dumped as well.
+//
+//  By default, the output is written to stdout.  Otherwise, a file
+//  bad-<lit>.vcd is written in the directory given by -o for each trace.
+//
+//  ⎣ ⇨ reach pack -h
+//  reach pack [opts] <output0> [<output1>, ...]
+//    -o string
+//      	directory for archives. (default ".")
+//    -rm
+//      	remove output directories once archived.
+//
+//  pack writes each output directory as a single file archive <dir>.reach in the
+//  directory given by -o.  The archive is a gzip compressed tar file containing a
+//  copy of the aiger, the manifest and the traces, invariants and certificates of
+//  the results, so unlike an output directory it does not depend on a symlink to
+//  the aiger and may be moved between machines.
+//
+//  Archives may be given in place of output directories to ck, cert, invmin,
+//  cexmin, stim, aag, aig, vcd and info.  Results stored by cert, invmin and
+//  cexmin are written back to the archive.
+//
+//  If any output fails to be archived, reach exits with status 1.
+//
+//  ⎣ ⇨ reach report -h
+//  reach report [opts] <dir0> [<dir1>, ...]
+//         reach report [opts] -cmp <old> <new>
+//    -ck
+//      	verify results.
+//    -cmp
+//      	compare the results in two trees.
+//    -dur duration
+//      	time limit for checking each invariant. (default 5s)
+//    -f string
+//      	report format (csv, md, html). (default "md")
+//    -j int
+//      	number of concurrent verification workers (default number of CPUs).
+//    -o string
+//      	output path.
+//    -slow float
+//      	factor by which durations must change for -cmp. (default 1.5)
+//
+//  report walks the given directories to find reach output directories and
+//  archives, and writes a table of the results with the output, bad state,
+//  status, depth, duration, engine and verification status of each result,
+//  followed by totals and the number of bad states solved by each engine.
+//
+//  -f selects the format: csv, md (Markdown) or html, a self-contained page.
+//  CSV output contains only the table of results, for further processing.  By
+//  default, the report is written to stdout.
+//
+//  With -ck, each result is verified as by "reach ck", by -j workers with a
+//  time limit of -dur for each invariant, and report exits with status 1 if
+//  any result fails verification.  Otherwise the verification status is "-".
+//
+//  With -cmp, report compares the results in two trees, such as the output
+//  roots of two runs over the same aigers.  Results are matched by their bad
+//  state and the path of their output directory or archive relative to the
+//  root of its tree, without the .reach extension.  Each result is marked as
+//  "lost" if it is solved only in <old>, "gained" if it is solved only in
+//  <new>, "contradiction" if the two disagree on reachability, and "slower" or
+//  "faster" if both are solved and the duration changed by more than the
+//  factor -slow and by at least 100ms.  The totals give the number solved and
+//  the total duration in each tree.  If there are any lost, slower or
+//  contradicting results, then report exits with status 1.
+//
+//  report exits with status 2 if no directories are given, or if -cmp is not
+//  given two directories.
+//
+//  ⎣ ⇨ reach diff -h
+//  reach diff [opts] <outA> <outB>
+//    -v	verbose, list unmatched results.
+//
+//  diff compares the results in two output directories or archives, or two trees
+//  of them.  Results are matched by the sha256 hash of their aiger and their bad
+//  state, so outputs of the same model may be compared wherever they are stored.
+//
+//  For each matched result, diff prints changes in status, depth, trace length
+//  and invariant size (clauses and literals).  Results present in only one of
+//  <outA> and <outB> are listed with -v.  A result which is reachable in one
+//  and unreachable in the other is a contradiction, which is printed as an
+//  error since one of the two must be wrong.
+//
+//  If a tree holds several results for the same aiger hash and bad state, the
+//  first one found is compared.  Later ones which contradict it are printed as
+//  contradictions too, and the others are listed with -v.
+//
+//  diff exits with status 1 if there are any contradictions, with status 2 if
+//  an output cannot be read, and with status 0 otherwise.
+//
+//  ⎣ ⇨ reach bench -h
+//  reach bench [opts] <dir> <engine> [engine opts]
+//    -j int
+//      	number of concurrent jobs (default number of CPUs).
+//    -mem int
+//      	memory limit per job in megabytes (0 for none).
+//    -o string
+//      	output directory. (default "bench")
+//    -res string
+//      	results file (default <o>/results.csv).
+//    -time duration
+//      	wall-clock limit per job. (default 1m0s)
+//
+//  bench runs the engine iic, bmc or sim with the given engine options on each
+//  aiger (.aig or .aag) under <dir>, with -j jobs at a time.  Each job is a
+//  separate reach process, so a crash or exhausted memory only affects its own
+//  aiger.  The output of a job is stored under the directory -o, in the same
+//  relative location as the aiger in <dir>, with the messages of the process
+//  in <output>.log.
+//
+//  -time limits the wall-clock time of each job.  When it is exceeded, the job
+//  is sent SIGTERM, so that it stores the results found so far, and is killed
+//  if it has not exited 5 seconds later.  -mem limits the address space of each
+//  job, in megabytes.  The engine's own time limit (-dur) should be smaller
+//  than -time.
+//
+//  A row is appended to the results file -res for each result of each job, as
+//  soon as the job ends.  Rows have the fields of results shown by "reach info"
+//  (M, Status, Depth, Dur, Engine, Options, Seed, Version, AigerHash, TraceLen,
+//  InvClauses), with durations in nanoseconds as in the stored json, preceded
+//  by the fields of the job: Aiger, Output, Exit (the exit status, or -1 if the
+//  job was killed by a signal), Error, Timeout, Wall and MaxRSS (in kilobytes).
+//  A job with no results has one row with the job fields only.  The results
+//  file is CSV, or newline delimited json if its name ends in ".json".
+//
+//  bench is resumable: aigers which already have rows in the results file are
+//  skipped, and outputs left by interrupted jobs are merged into.
+//
+//  ⎣ ⇨ reach tune -h
+//  reach tune [opts] <aiger | dir> [<aiger | dir>, ...]
+//    -budget duration
+//      	time budget for tuning. (default 10m0s)
+//    -dur duration
+//      	time limit for each run. (default 10s)
+//    -factor float
+//      	cost factor for discarding configurations. (default 1.5)
+//    -j int
+//      	number of concurrent runs. (default 1)
+//    -n int
+//      	maximum number of configurations. (default 32)
+//    -o string
+//      	options file for the best configuration. (default "iic-opts.json")
+//    -seed int
+//      	random seed. (default 44)
+//
+//  tune searches the combinations of the iic switches Preprocess, FilterObs,
+//  ConsecuSift, ConsecuSiftPull, Justify, DeepObs and GnrlRemoveLits for the
+//  configuration which solves the bad states of the given aigers (or the aigers
+//  under the given directories) fastest.  The compile time constants in iic/cfg.go
+//  are not tuned.
+//
+//  The configurations, at most -n of them chosen at random besides the defaults,
+//  race over the bad states in random order.  Each configuration runs iic on
+//  each bad state with a time limit of -dur, and costs the time taken if it
+//  solves the bad state, or twice -dur if it does not.  After the first 3 bad
+//  states, configurations are discarded if they cost both more than -factor
+//  times the best configuration so far and more than 100ms above it.  The race
+//  stops when all bad states have been raced or the time budget -budget is
+//  spent.  No run exceeds the time left in the budget, and a bad state on which
+//  the budget runs out is not counted.
+//
+//  If configurations disagree on whether a bad state is reachable, their traces
+//  and invariants are verified, and configurations whose results do not verify
+//  within -dur are discarded.
+//
+//  The best remaining configuration is written as a json file of iic.Options to
+//  -o, which may be given to "reach iic -opts".  Its Duration is the default of
+//  iic.Options, not -dur; "reach iic" takes its time limit from its own -dur.  A table of the remaining
+//  configurations is printed, in which each configuration is shown with a bit
+//  for each switch, in the order above.  -j configurations are run at a time;
+//  as they compete for the machine, timings are most reliable with -j 1.
+//
+//  ⎣ ⇨ reach run -h
+//  reach run [opts] <job.json>
+//    -n	print the commands without running them.
+//
+//  run reads a job file, which describes the aigers to check, the engines to
+//  check them with and the steps to take with the results, so that verification
+//  jobs may be kept under version control.  A job file is json such as
+//
+//    {
+//      "Aigers": ["models/*.aig", "more"],
+//      "Props": [0, 2],
+//      "Output": "out",
+//      "J": 4,
+//      "Time": "5m",
+//      "Budget": "2h",
+//      "Mem": 4096,
+//      "Engines": [
+//        {"Engine": "bmc", "Options": {"Duration": 10000000000, "MaxDepth": 50}},
+//        {"Engine": "iic", "Options": {"Duration": 60000000000, "DeepObs": false}},
+//        {"Engine": "sim", "Options": {"N": 8}, "Args": ["-v"]}
+//      ],
+//      "Post": [
+//        {"Step": "ck", "Args": ["-j", "4"]},
+//        {"Step": "cexmin", "Args": ["-dur", "1m"]},
+//        {"Step": "report", "Args": ["-f", "html", "-o", "out/report.html"]}
+//      ]
+//    }
+//
+//  Aigers lists aiger files, glob patterns and directories containing aigers.
+//  Props selects the bad states to check by index, all of them if absent.
+//  Output is the output root, in which the output of each aiger is stored in
+//  the same relative location as the aiger under the directory of the job file.
+//  Relative paths in the job file, including in Args, are relative to the
+//  directory of the job file, in which the engines and steps are run.
+//
+//  The engines are run in order on each aiger, with J aigers at a time, each
+//  engine in a separate reach process as with "reach bench", merging their
+//  results into the output.  Once the selected bad states are solved, the
+//  remaining engines are skipped.  Time limits each engine process as -time
+//  does for bench, Mem limits its memory in megabytes, and Budget limits the
+//  whole job: once it is spent no more engines are started.  Time and Budget
+//  are durations such as "90s"; absent or zero, they do not limit.
+//
+//  The Options of an engine are the json fields of iic.Options for iic, of
+//  sim.Options for sim, and Duration and MaxDepth for bmc, with durations in
+//  nanoseconds.  Fields which are absent take the defaults of the engine's
+//  command.  Args gives further flags of the engine's command.  The messages
+//  of the engines are written to <output>.log.
+//
+//  The post-steps ck, invmin, cexmin, cert and pack are run once on all outputs
+//  of the job, and report on the output root, each with the given Args.  Steps
+//  are run in order after all engines.
+//
+//  run exits with status 1 if the job file is invalid, if an engine fails
+//  other than by reaching its time limit, or if a post-step fails.
+//
+//  ⎣ ⇨ reach hwmcc -h
+//  reach hwmcc [opts] <aiger>
+//    -dur duration
+//      	time limit. (default 1h0m0s)
+//    -e string
+//      	comma separated engines of the portfolio. (default "iic,bmc,sim")
+//    -o string
+//      	output directory (default temporary).
+//    -p string
+//      	json file of the portfolio, overriding -e.
+//
+//  hwmcc checks all bad states of a single aiger with a portfolio of engines
+//  run at the same time, and prints the results on stdout as aiger 1.9 (HWMCC)
+//  witnesses, one for each bad state in order: a counterexample trace for a
+//  reachable bad state, "0" for an unreachable one and "2" for an unknown one.
+//  All other messages are printed on stderr.
+//
+//  The portfolio is given by -e, a comma separated list of the engines iic,
+//  bmc and sim with their default options, or by -p, a json file with a list
+//  of engines as in the Engines of "reach run" job files.  Each engine is run
+//  in a separate reach process, merging its results into the output, and the
+//  portfolio stops once every bad state is solved.
+//
+//  -dur limits the time of the whole portfolio, and of each engine whose
+//  options do not give a duration.  If hwmcc receives SIGINT or SIGTERM, it
+//  stops the engines and prints the results found so far.  Results are stored
+//  in the output directory -o, or in a temporary directory which is removed if
+//  -o is not given.
+//
+//  hwmcc exits with status 10 if any bad state is reachable, with status 20 if
+//  all bad states are unreachable, and with status 0 otherwise, as in HWMCC.
+//  If the aiger or the portfolio cannot be read, it exits with status 1 before
+//  running any engine.
+//
 //  ⎣ ⇨ reach info -h
 //  reach info [opts] <aiger | output>
+//    -f string
+//      	format for bad state json (eg -f '{{.FormatStatus}}').
 //    -v	verbose, provide more info.
 //
 //  info provides information about an aiger or output directory of reach.
 //
+//  For output directories, -f formats each stored result with a Go template.
+//  Besides the status (.FormatStatus), depth and duration, results record the
+//  engine and options which produced them, the random seed, the reach version,
+//  the sha256 hash of the aiger, the trace length, the number of invariant
+//  clauses and engine statistics such as {{.Stats.SatCalls}}.
+//
 package main
@@ -0,0 +1,171 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-air/gini/logic/aiger"
+	"github.com/go-air/gini/z"
+	"github.com/go-air/reach"
+	"github.com/go-air/reach/sim"
+)
+
+var jsonEvents = reachFlags.Bool("json", false, "print newline delimited json events on stdout")
+
+var jsonDoc = `With -json, iic, bmc, sim, ck, info, stim, aag and aig print one json object
+per line on stdout for each event, and their other messages on stderr.  The
+field Event is one of
+
+	start         a command starts on an aiger or output.
+	progress      an engine proceeds: iic starts a bad state, sim has
+	              taken Steps steps.
+	result        a result is known or read: Result holds the result as
+	              stored in outputs, Aig the sizes of an aiger for info, File
+	              the file written by stim, aag or aig, or Data the contents
+	              they would write to stdout, base64 encoded.
+	verification  ck has verified a result: Check is ok, FAIL or none, with
+	              Errors and the duration Dur.
+	error         a command failed, as described by Error.
+
+Events also have the fields Time, Cmd, and Aiger, Output and File where they
+apply.  Durations are in nanoseconds.  Other commands print no events, and
+-json does not change their output.`
+
+// eventCmds are the commands which print events with -json, by name.
+var eventCmds = map[string]bool{"iic": true, "bmc": true, "sim": true, "ck": true, "info": true,
+	"stim": true, "aag": true, "aig": true}
+
+// stdout is the standard output of reach, which initEvents may replace by
+// stderr in os.Stdout.
+var stdout = os.Stdout
+
+// eventOut is where events are written.
+var eventOut io.Writer = stdout
+
+var eventMu sync.Mutex
+
+// jsonStepEvery is the number of simulation steps between progress events.
+const jsonStepEvery = 1 << 20
+
+// event is an event printed with -json.  The field names are stable.
+type event struct {
+	Event   string
+	Time    time.Time
+	Cmd     string
+	Aiger   string        `json:",omitempty"`
+	Output  string        `json:",omitempty"`
+	File    string        `json:",omitempty"`
+	Options string        `json:",omitempty"`
+	Bad     z.Lit         `json:",omitempty"`
+	Steps   int64         `json:",omitempty"`
+	Result  *reach.Result `json:",omitempty"`
+	Aig     *eventAig     `json:",omitempty"`
+	Data    []byte        `json:",omitempty"`
+	Check   string        `json:",omitempty"`
+	Errors  []string      `json:",omitempty"`
+	Dur     time.Duration `json:",omitempty"`
+	Error   string        `json:",omitempty"`
+}
+
+// eventAig gives the sizes of an aiger.
+type eventAig struct {
+	Latches int
+	Inputs  int
+	Nodes   int
+	Bads    int
+}
+
+func newEventAig(g *aiger.T) *eventAig {
+	return &eventAig{Latches: len(g.Sys().Latches), Inputs: len(g.Inputs), Nodes: g.Sys().Len(),
+		Bads: len(aigerBad(g))}
+}
+
+// initEvents directs the messages of `cmd` to stderr with -json, if it
+// prints events, so that stdout holds only its events.
+func initEvents(cmd *subCmd) {
+	if *jsonEvents && eventCmds[cmd.Name] {
+		os.Stdout = os.Stderr
+	}
+}
+
+// emit prints `e` with -json.
+func emit(e *event) {
+	if !*jsonEvents {
+		return
+	}
+	e.Time = time.Now()
+	eventMu.Lock()
+	defer eventMu.Unlock()
+	if err := json.NewEncoder(eventOut).Encode(e); err != nil {
+		log.Printf("error writing event: %s", err)
+	}
+}
+
+// emitError emits an error event for command `cmd` on `arg`, which is an
+// aiger if `isAiger` and an output otherwise.
+func emitError(cmd *subCmd, arg string, isAiger bool, err error) {
+	e := &event{Event: "error", Cmd: cmd.Name, Error: err.Error()}
+	if isAiger {
+		e.Aiger = arg
+	} else {
+		e.Output = arg
+	}
+	emit(e)
+}
+
+// emitResults emits a result event for each result of `out` for a bad state
+// in `bad`, found by command `cmd` on aiger `fn`.
+func emitResults(cmd *subCmd, fn string, out *reach.Output, bad []z.Lit) {
+	if !*jsonEvents {
+		return
+	}
+	sel := make(map[z.Lit]bool)
+	for _, m := range bad {
+		sel[m] = true
+	}
+	for _, b := range out.Results() {
+		if sel[b.M] {
+			emit(&event{Event: "result", Cmd: cmd.Name, Aiger: fn, Output: out.RootDir(), Result: b})
+		}
+	}
+}
+
+// eventWriter collects data written to stdout without -json, and emits it
+// as a result event when closed.
+type eventWriter struct {
+	bytes.Buffer
+	e *event
+}
+
+func (w *eventWriter) Close() error {
+	w.e.Data = w.Bytes()
+	emit(w.e)
+	return nil
+}
+
+// simProgress is a sim.Observer which emits progress events.
+type simProgress struct {
+	cmd *subCmd
+	fn  string
+}
+
+func (p *simProgress) OnWatch(t *sim.T, m z.Lit, l sim.Lane) sim.Action {
+	return sim.Continue
+}
+
+func (p *simProgress) OnStep(t *sim.T) sim.Action {
+	emit(&event{Event: "progress", Cmd: p.cmd.Name, Aiger: p.fn, Steps: t.Steps()})
+	return sim.Continue
+}
+
+func (p *simProgress) OnRestart(t *sim.T, n int) sim.Action {
+	return sim.Continue
+}
@@ -0,0 +1,278 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-air/gini/z"
+	"github.com/go-air/reach"
+)
+
+var hwmccCmd = &subCmd{
+	Name:  "hwmcc",
+	Flags: flag.NewFlagSet("hwmcc", flag.ExitOnError),
+	Run:   doHwmcc,
+	Init:  initHwmcc,
+	Usage: "reach hwmcc [opts] <aiger>",
+	Short: `hwmcc checks an aiger with a portfolio of engines, HWMCC style.`,
+	Long: `
+hwmcc checks all bad states of a single aiger with a portfolio of engines
+run at the same time, and prints the results on stdout as aiger 1.9 (HWMCC)
+witnesses, one for each bad state in order: a counterexample trace for a
+reachable bad state, "0" for an unreachable one and "2" for an unknown one.
+All other messages are printed on stderr.
+
+The portfolio is given by -e, a comma separated list of the engines iic,
+bmc and sim with their default options, or by -p, a json file with a list
+of engines as in the Engines of "reach run" job files.  Each engine is run
+in a separate reach process, merging its results into the output, and the
+portfolio stops once every bad state is solved.
+
+-dur limits the time of the whole portfolio, and of each engine whose
+options do not give a duration.  If hwmcc receives SIGINT or SIGTERM, it
+stops the engines and prints the results found so far.  Results are stored
+in the output directory -o, or in a temporary directory which is removed if
+-o is not given.
+
+hwmcc exits with status 10 if any bad state is reachable, with status 20 if
+all bad states are unreachable, and with status 0 otherwise, as in HWMCC.
+If the aiger or the portfolio cannot be read, it exits with status 1 before
+running any engine.
+`}
+
+var hwmccOpts = struct {
+	Engines   *string
+	Portfolio *string
+	Dur       *time.Duration
+	Dir       *string
+}{}
+
+// exit codes of hwmcc, as for checking external results with ck.
+const (
+	hwmccExitSat     = ckExitSat
+	hwmccExitUnsat   = ckExitUnsat
+	hwmccExitUnknown = ckExitUnknown
+	hwmccExitFail    = ckExitFail
+)
+
+func initHwmcc(cmd *subCmd) {
+	flags := cmd.Flags
+	hwmccOpts.Engines = flags.String("e", "iic,bmc,sim", "comma separated engines of the portfolio.")
+	hwmccOpts.Portfolio = flags.String("p", "", "json file of the portfolio, overriding -e.")
+	hwmccOpts.Dur = flags.Duration("dur", time.Hour, "time limit.")
+	hwmccOpts.Dir = flags.String("o", "", "output directory (default temporary).")
+	flags.Usage = func() {
+		fmt.Println(cmd.Usage)
+		flags.PrintDefaults()
+		fmt.Println(cmd.Long)
+	}
+}
+
+func doHwmcc(cmd *subCmd, args []string) {
+	flags := cmd.Flags
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.SetOutput(os.Stderr)
+		flags.Usage()
+		os.Exit(2)
+	}
+	// witnesses only on stdout.
+	os.Stdout = os.Stderr
+	code, err := hwmcc(stdout, flags.Arg(0))
+	if err != nil {
+		log.Printf("hwmcc: %s", err)
+	}
+	os.Exit(code)
+}
+
+// hwmcc runs the portfolio on aiger `fn` and writes the witnesses to `w`.
+// It returns the exit code, and an error if the portfolio could not be run
+// or its results could not be read.
+func hwmcc(w io.Writer, fn string) (int, error) {
+	aig, err := readAiger(fn)
+	if err != nil {
+		return hwmccExitFail, err
+	}
+	bad := aigerBad(aig)
+	if len(bad) == 0 {
+		return hwmccExitFail, fmt.Errorf("%s: no bad states", fn)
+	}
+	engines, err := hwmccEngines()
+	if err != nil {
+		return hwmccExitFail, err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return hwmccExitFail, err
+	}
+	tmp, err := ioutil.TempDir("", "reach-hwmcc")
+	if err != nil {
+		return hwmccExitFail, err
+	}
+	defer os.RemoveAll(tmp)
+	dir := *hwmccOpts.Dir
+	if dir == "" {
+		dir = tmp
+	}
+	base := filepath.Base(fn)
+	opath := filepath.Join(dir, strings.TrimSuffix(base, filepath.Ext(base)))
+	var cs []*exec.Cmd
+	for i, e := range engines {
+		eargs, err := e.args("", *hwmccOpts.Dur, filepath.Join(tmp, fmt.Sprintf("%d-%s.json", i, e.Engine)))
+		if err != nil {
+			return hwmccExitFail, fmt.Errorf("engine %d (%s): %w", i, e.Engine, err)
+		}
+		args := []string{"-merge", "-trace", *traceFmtName, e.Engine}
+		args = append(args, eargs...)
+		args = append(args, "-o", dir, fn)
+		c := exec.Command(exe, args...)
+		c.Stdout, c.Stderr = os.Stderr, os.Stderr
+		cs = append(cs, c)
+	}
+	hwmccPortfolio(cs, opath, bad)
+	return hwmccWitnesses(w, opath, bad)
+}
+
+// hwmccEngines returns the engines of the portfolio.
+func hwmccEngines() ([]*runEngine, error) {
+	var engines []*runEngine
+	if *hwmccOpts.Portfolio != "" {
+		if err := readJSONFile(*hwmccOpts.Portfolio, &engines); err != nil {
+			return nil, fmt.Errorf("reading portfolio: %w", err)
+		}
+	} else {
+		for _, name := range strings.Split(*hwmccOpts.Engines, ",") {
+			engines = append(engines, &runEngine{Engine: strings.TrimSpace(name)})
+		}
+	}
+	if len(engines) == 0 {
+		return nil, fmt.Errorf("empty portfolio")
+	}
+	for _, e := range engines {
+		if engineCmds[e.Engine] == nil {
+			return nil, fmt.Errorf("unknown engine '%s'", e.Engine)
+		}
+	}
+	return engines, nil
+}
+
+// hwmccPortfolio runs the engine processes `cs` until they have all exited,
+// stopping them once the bad states `bad` are solved in the output `opath`,
+// once the time limit is reached or on SIGINT or SIGTERM.
+func hwmccPortfolio(cs []*exec.Cmd, opath string, bad []z.Lit) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	done := make(chan *exec.Cmd, len(cs))
+	running := make(map[*exec.Cmd]bool)
+	for _, c := range cs {
+		if err := c.Start(); err != nil {
+			log.Printf("hwmcc: %s", err)
+			continue
+		}
+		running[c] = true
+		go func(c *exec.Cmd) {
+			c.Wait()
+			done <- c
+		}(c)
+	}
+	limit := time.After(*hwmccOpts.Dur)
+	var kill <-chan time.Time
+	stop := func(why string) {
+		if kill != nil {
+			return
+		}
+		log.Printf("hwmcc: %s, stopping engines", why)
+		for c := range running {
+			terminate(c.Process)
+		}
+		kill = time.After(benchGrace)
+	}
+	for len(running) != 0 {
+		select {
+		case c := <-done:
+			delete(running, c)
+			if runSolved(opath, bad) {
+				stop("solved")
+			}
+		case <-limit:
+			stop("time limit reached")
+		case sig := <-sigs:
+			stop(sig.String())
+		case <-kill:
+			for c := range running {
+				c.Process.Kill()
+			}
+		}
+	}
+}
+
+// hwmccWitnesses writes the witnesses of the bad states `bad` in the output
+// `opath` to `w`, and returns the exit code.
+func hwmccWitnesses(w io.Writer, opath string, bad []z.Lit) (int, error) {
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+	out, err := reach.OpenOutput(opath)
+	if err != nil {
+		for i := range bad {
+			reach.EncodeAigerStatus(bw, 0, i)
+		}
+		return hwmccExitUnknown, err
+	}
+	defer out.Close()
+	aig, err := out.Aiger()
+	if err != nil {
+		return hwmccExitUnknown, err
+	}
+	res := make(map[z.Lit]int)
+	for j, b := range out.Results() {
+		res[b.M] = j
+	}
+	nSat, nUnsat := 0, 0
+	for i, m := range bad {
+		j, ok := res[m]
+		var b *reach.Result
+		if ok {
+			b = out.Results()[j]
+		}
+		switch {
+		case b != nil && b.IsReachable():
+			tr, err := out.Trace(j)
+			if err != nil {
+				log.Printf("hwmcc: reading trace of bad %d: %s", i, err)
+				reach.EncodeAigerStatus(bw, 0, i)
+				continue
+			}
+			if err := tr.EncodeAigerWitness(bw, aig, i); err != nil {
+				return hwmccExitUnknown, err
+			}
+			nSat++
+		case b != nil && b.IsUnreachable():
+			reach.EncodeAigerStatus(bw, -1, i)
+			nUnsat++
+		default:
+			reach.EncodeAigerStatus(bw, 0, i)
+		}
+	}
+	switch {
+	case nSat != 0:
+		return hwmccExitSat, nil
+	case nUnsat == len(bad):
+		return hwmccExitUnsat, nil
+	}
+	return hwmccExitUnknown, nil
+}
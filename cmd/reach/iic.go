@@ -10,7 +10,7 @@ import (
 	"os"
 	"time"
 
-	"github.com/go-air/reach"
+	"github.com/go-air/gini/z"
 	"github.com/go-air/reach/iic"
 )
 
@@ -31,6 +31,21 @@ unreachable bad states.
 iic counterexamples are not necessarily shortest counterexamples. Bad state
 depths for traces are the trace length itself.  For unknown results, depths
 represent the depth to which it is known no counterexample trace exists.
+
+The result for each bad state is stored as soon as it is found.  If reach
+receives SIGINT or SIGTERM, iic stops between SAT calls and stores the
+depth reached for the current bad state as an unknown result, without
+checking further bad states or aigers.  The clauses of the frame at the
+depth reached are stored with the unknown result in "<lit>-frames.cnf", in
+dimacs format, and listed in the manifest; they are not an invariant and
+are not verified.  If iic has not stopped 3 seconds later, or on a second
+signal, reach exits keeping only the results already stored.  After a
+signal, reach exits with status 128 plus the signal number: 130 for SIGINT
+and 143 for SIGTERM.
+
+-opts reads the options from a json file of iic.Options, such as the best
+configuration found by "reach tune".  Flags given explicitly override the
+options in the file, except -dur, which always sets the time limit.
 `}
 
 var iicOpts = struct {
@@ -42,6 +57,10 @@ var iicOpts = struct {
 	ConsecSiftPull *bool
 	FilterObs      *bool
 	Preprocess     *bool
+	DeepObs        *bool
+	RemoveLits     *bool
+	OptsFile       *string
+	Props          *string
 }{}
 
 func initIic(cmd *subCmd) {
@@ -54,6 +73,10 @@ func initIic(cmd *subCmd) {
 	iicOpts.ConsecSiftPull = flags.Bool("pull", true, "do pulling with consecutive sifting.")
 	iicOpts.FilterObs = flags.Bool("filter", true, "filter proof obligations.")
 	iicOpts.Preprocess = flags.Bool("pp", true, "pre-process aig.")
+	iicOpts.DeepObs = flags.Bool("deep", true, "keep deep proof obligations.")
+	iicOpts.RemoveLits = flags.Bool("rmlits", false, "remove literals when generalizing.")
+	iicOpts.OptsFile = flags.String("opts", "", "json options file, as written by \"reach tune\".")
+	iicOpts.Props = flags.String("props", "", propsDoc)
 	flags.StringVar(&outDir, "o", ".", "output directory")
 	flags.Usage = func() {
 		fmt.Println(cmd.Usage)
@@ -68,62 +91,118 @@ func doIic(cmd *subCmd, args []string) {
 	if flags.NArg() == 0 {
 		fmt.Fprintf(os.Stderr, "no aigs specified.\n")
 	}
-	for i := 0; i < flags.NArg(); i++ {
+	for i := 0; i < flags.NArg() && !interrupted(); i++ {
 		arg := flags.Arg(i)
-		if err := doIicAiger(arg, *iicOpts.Dur); err != nil {
+		if err := doIicAiger(cmd, arg, *iicOpts.Dur); err != nil {
 			fmt.Fprintf(os.Stderr, "error doing '%s': %s\n", arg, err)
+			emitError(cmd, arg, true, err)
 			continue
 		}
 	}
 }
 
-func doIicAiger(fn string, dur time.Duration) error {
+func doIicAiger(cmd *subCmd, fn string, dur time.Duration) error {
 	start := time.Now()
 	aig, err := readAiger(fn)
 	if err != nil {
 		return err
 	}
 	fmt.Printf("read %s in %s\n", fn, time.Since(start))
-	bad := aigerBad(aig)
+	bad, err := selectBad(aigerBad(aig), *iicOpts.Props)
+	if err != nil {
+		return err
+	}
 	if len(bad) == 0 {
 		return fmt.Errorf("ErrNoBads")
 	}
+	base, err := iicOptions(cmd.Flags)
+	if err != nil {
+		return err
+	}
+	out, err := makeOutput(cmd, fn)
+	if err != nil {
+		return fmt.Errorf("making output: %w", err)
+	}
+	sigs := stopOnSignal()
+	defer sigs.close()
+	emit(&event{Event: "start", Cmd: cmd.Name, Aiger: fn, Output: out.RootDir(), Options: flagOptions(cmd.Flags)})
 	trans := aig.S
 	for _, b := range bad {
+		if interrupted() {
+			break
+		}
+		emit(&event{Event: "progress", Cmd: cmd.Name, Aiger: fn, Bad: b})
 		mc := iic.New(trans, b)
 		if *iicOpts.Verbose {
 			fmt.Printf("created mc in %s\n", time.Since(start))
 		}
-		opts := mc.Options()
-		opts.Verbose = *iicOpts.Verbose
-		opts.Justify = *iicOpts.Justify
-		opts.Preprocess = *iicOpts.Preprocess
-		opts.Duration = *iicOpts.Dur
-		opts.ConsecuSift = *iicOpts.ConsecSift
-		opts.ConsecuSiftPull = *iicOpts.ConsecSiftPull
-		opts.FilterObs = *iicOpts.FilterObs
-		opts.MaxDepth = *iicOpts.MaxDepth
+		*mc.Options() = *base
+		sigs.setStop(mc.Stop)
 
-		switch mc.Try() {
+		res, err := mc.Try()
+		if err != nil {
+			fmt.Printf("%s: error: %s\n", fn, err)
+			emitError(cmd, fn, true, err)
+		}
+		switch res {
 		case 1:
 			fmt.Printf("%s: cex found.\n", fn)
 		case -1:
 			fmt.Printf("%s: inv found.\n", fn)
 		case 0:
-			fmt.Printf("%s: timeout.\n", fn)
+			if interrupted() {
+				fmt.Printf("%s: stopped.\n", fn)
+			} else {
+				fmt.Printf("%s: timeout.\n", fn)
+			}
 		default:
 			panic("unreachable")
 		}
-		out, err := reach.MakeOutput(fn, outDir)
-		if err != nil {
-			log.Printf("%s: error making output: %s", fn, err)
-			continue
+		if err := mc.FillOutput(out); err != nil {
+			log.Printf("%s: %s", fn, err)
 		}
-		mc.FillOutput(out)
+		// store each result as it is found.
 		if err := out.Store(); err != nil {
-			log.Printf("error storing output: %s", err)
+			return fmt.Errorf("storing output: %w", err)
 		}
-		fmt.Printf("wrote results in %s.\n", out.RootDir())
+		emitResults(cmd, fn, out, []z.Lit{b})
 	}
+	fmt.Printf("wrote results in %s.\n", out.RootDir())
 	return nil
 }
+
+// iicOptions returns the iic options given by `flags`.  With -opts, the
+// options are read from the file and only flags which are set override
+// them, but the time limit is always -dur.
+func iicOptions(flags *flag.FlagSet) (*iic.Options, error) {
+	opts := iic.NewOptions()
+	if *iicOpts.OptsFile != "" {
+		if err := readJSONFile(*iicOpts.OptsFile, opts); err != nil {
+			return nil, fmt.Errorf("reading options: %w", err)
+		}
+	}
+	set := setFlags(flags)
+	for _, o := range []struct {
+		name string
+		dst  *bool
+		src  *bool
+	}{
+		{"v", &opts.Verbose, iicOpts.Verbose},
+		{"justify", &opts.Justify, iicOpts.Justify},
+		{"pp", &opts.Preprocess, iicOpts.Preprocess},
+		{"csift", &opts.ConsecuSift, iicOpts.ConsecSift},
+		{"pull", &opts.ConsecuSiftPull, iicOpts.ConsecSiftPull},
+		{"filter", &opts.FilterObs, iicOpts.FilterObs},
+		{"deep", &opts.DeepObs, iicOpts.DeepObs},
+		{"rmlits", &opts.GnrlRemoveLits, iicOpts.RemoveLits},
+	} {
+		if set[o.name] {
+			*o.dst = *o.src
+		}
+	}
+	opts.Duration = *iicOpts.Dur
+	if set["to"] {
+		opts.MaxDepth = *iicOpts.MaxDepth
+	}
+	return opts, nil
+}
@@ -23,6 +23,12 @@ var infoCmd = &subCmd{
 	Short: `info provides summary information about an aiger or output.`,
 	Long: `
 info provides information about an aiger or output directory of reach.
+
+For output directories, -f formats each stored result with a Go template.
+Besides the status (.FormatStatus), depth and duration, results record the
+engine and options which produced them, the random seed, the reach version,
+the sha256 hash of the aiger, the trace length, the number of invariant
+clauses and engine statistics such as {{.Stats.SatCalls}}.
 `}
 
 var infoOpts = struct {
@@ -50,6 +56,7 @@ func doInfo(cmd *subCmd, args []string) {
 	for _, arg := range flags.Args() {
 		if err := doInfoArg(cmd, arg); err != nil {
 			log.Printf("error doing '%s': %s", arg, err.Error())
+			emitError(cmd, arg, false, err)
 		}
 	}
 }
@@ -59,17 +66,19 @@ func doInfoArg(cmd *subCmd, arg string) error {
 	if os.IsNotExist(err) {
 		return err
 	}
-	if st.IsDir() {
-		return doInfoOutput(arg)
+	if st.IsDir() || reach.IsArchive(arg) {
+		return doInfoOutput(cmd, arg)
 	}
-	return doInfoAig(arg)
+	return doInfoAig(cmd, arg)
 }
 
-func doInfoOutput(arg string) error {
+func doInfoOutput(cmd *subCmd, arg string) error {
 	out, err := reach.OpenOutput(arg)
 	if err != nil {
 		return err
 	}
+	defer out.Close()
+	emit(&event{Event: "start", Cmd: cmd.Name, Output: arg})
 	var tmpl *template.Template
 	if *infoOpts.Format != "" {
 		tmpl, err = template.New("reach").Parse(*infoOpts.Format)
@@ -78,6 +87,7 @@ func doInfoOutput(arg string) error {
 		}
 	}
 	for _, b := range out.Results() {
+		emit(&event{Event: "result", Cmd: cmd.Name, Output: arg, Result: b})
 		if *infoOpts.Verbose {
 			fmt.Printf("%s ", out.AigerPath())
 		}
@@ -94,7 +104,7 @@ func doInfoOutput(arg string) error {
 	return nil
 }
 
-func doInfoAig(arg string) error {
+func doInfoAig(cmd *subCmd, arg string) error {
 	f, e := os.Open(arg)
 	if e != nil {
 		return e
@@ -104,6 +114,8 @@ func doInfoAig(arg string) error {
 	if err != nil {
 		return err
 	}
+	emit(&event{Event: "start", Cmd: cmd.Name, Aiger: arg})
+	emit(&event{Event: "result", Cmd: cmd.Name, Aiger: arg, Aig: newEventAig(aig)})
 	fmt.Printf("aig %s:\n", arg)
 	fmt.Printf("\t%d latches\n\t%d inputs\n\t%d total\n\t%d bads\n", len(aig.Sys().Latches),
 		len(aig.Inputs), aig.Sys().Len(), len(aigerBad(aig)))
@@ -0,0 +1,90 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-air/reach"
+)
+
+var invminCmd = &subCmd{
+	Name:  "invmin",
+	Flags: flag.NewFlagSet("invmin", flag.ExitOnError),
+	Run:   doInvmin,
+	Init:  initInvmin,
+	Usage: "reach invmin [opts] <output0> [<output1>, ...]",
+	Short: `invmin minimizes inductive invariants in output directories.`,
+	Long: `
+invmin minimizes the inductive invariants in reach output directories.  Clauses
+which are not needed for the invariant to be inductive and to exclude the bad
+state are removed, and then literals are removed from the remaining clauses.
+
+The smaller invariant is verified and, if verification succeeds, replaces the
+original invariant, as well as the aiger certificate if one has been written
+by "reach cert".  The sizes of the invariant before and after are reported.
+If any invariant fails to be minimized or verified, reach exits with status 1
+and the original invariant is kept.  If minimizing an invariant takes longer
+than -dur, the partially minimized invariant is verified and kept as above,
+with a warning.
+`}
+
+var invminOpts = struct {
+	Dur *time.Duration
+}{}
+
+func initInvmin(cmd *subCmd) {
+	flags := cmd.Flags
+	invminOpts.Dur = flags.Duration("dur", 30*time.Second, "time limit for minimizing each invariant.")
+	flags.Usage = func() {
+		fmt.Println(cmd.Usage)
+		flags.PrintDefaults()
+		fmt.Println(cmd.Long)
+	}
+}
+
+func doInvmin(cmd *subCmd, args []string) {
+	flags := cmd.Flags
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "no output directories specified.\n")
+		return
+	}
+	hasErr := false
+	for _, arg := range flags.Args() {
+		out, err := reach.OpenOutput(arg)
+		if err != nil {
+			fmt.Printf("error opening '%s': %s\n", arg, err)
+			hasErr = true
+			continue
+		}
+		for i, bad := range out.Results() {
+			if !bad.IsUnreachable() || !out.IsVerifiable(i) {
+				continue
+			}
+			before, after, errs := out.MinimizeInvariant(i, *invminOpts.Dur)
+			if len(errs) == 1 && errors.Is(errs[0], reach.ErrTimeout) {
+				fmt.Printf("\twarning minimizing %s: %s\n", bad, errs[0])
+				fmt.Printf("\tpartially minimized %s: %s -> %s\n", bad, before, after)
+				continue
+			}
+			if len(errs) != 0 {
+				for _, e := range errs {
+					fmt.Printf("\terror minimizing %s: %s\n", bad, e)
+				}
+				hasErr = true
+				continue
+			}
+			fmt.Printf("\tminimized %s: %s -> %s\n", bad, before, after)
+		}
+		out.Close()
+	}
+	if hasErr {
+		os.Exit(1)
+	}
+}
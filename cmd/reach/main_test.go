@@ -0,0 +1,86 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testMainEnv is set in the environment of the test binary when it is run
+// as reach, including by commands which run reach processes themselves.
+const testMainEnv = "REACH_TEST_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testMainEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runReach runs reach with arguments `args`, returning its stdout, its
+// stderr and its exit code.
+func runReach(t *testing.T, args ...string) (string, string, int) {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := exec.Command(exe, args...)
+	c.Env = append(os.Environ(), testMainEnv+"=1")
+	var stdout, stderr bytes.Buffer
+	c.Stdout, c.Stderr = &stdout, &stderr
+	err = c.Run()
+	var ee *exec.ExitError
+	if err != nil && !errors.As(err, &ee) {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), c.ProcessState.ExitCode()
+}
+
+// testAiger writes a binary aiger with a latch following an input, whose
+// bad state is the latch, to a temporary directory, and returns its path.
+func testAiger(t *testing.T) string {
+	p := filepath.Join(t.TempDir(), "follow.aig")
+	if err := ioutil.WriteFile(p, []byte("aig 2 1 1 0 0 1\n2\n4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestJSONReport(t *testing.T) {
+	fn := testAiger(t)
+	dir := t.TempDir()
+	if _, stderr, code := runReach(t, "bmc", "-o", dir, fn); code != 0 {
+		t.Fatalf("bmc exited with %d:\n%s", code, stderr)
+	}
+	stdout, stderr, code := runReach(t, "-json", "report", dir)
+	if code != 0 {
+		t.Fatalf("report exited with %d:\n%s", code, stderr)
+	}
+	if !strings.Contains(stdout, "reachable") {
+		t.Errorf("report not on stdout:\nstdout:\n%s\nstderr:\n%s", stdout, stderr)
+	}
+}
+
+func TestHwmccJSON(t *testing.T) {
+	fn := testAiger(t)
+	stdout, stderr, code := runReach(t, "-json", "hwmcc", "-e", "bmc", fn)
+	if code != hwmccExitSat {
+		t.Fatalf("hwmcc exited with %d:\n%s", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "1\nb0\n") || !strings.HasSuffix(stdout, ".\n") {
+		t.Errorf("no witness on stdout:\nstdout:\n%s\nstderr:\n%s", stdout, stderr)
+	}
+	if strings.Contains(stderr, "b0\n") {
+		t.Errorf("witness on stderr:\n%s", stderr)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-air/reach"
+)
+
+// makeOutput makes an output for aiger `fn` in the output directory
+// given by the -o flag, configured by global options.  The options of
+// `cmd` are recorded in its results.
+func makeOutput(cmd *subCmd, fn string) (*reach.Output, error) {
+	mk := reach.MakeOutput
+	if *mergeOut {
+		mk = reach.MergeOutput
+	}
+	out, err := mk(fn, outDir)
+	if err != nil {
+		return nil, err
+	}
+	out.SetTraceFormat(traceFmt)
+	out.SetOptions(cmd.Name, flagOptions(cmd.Flags))
+	return out, nil
+}
+
+// flagOptions formats the values of all flags in `flags` other than the
+// output directory.  If an options file is given with -opts, then only the
+// flags given explicitly are formatted, along with the file.
+func flagOptions(flags *flag.FlagSet) string {
+	var opts []string
+	set := setFlags(flags)
+	flags.VisitAll(func(f *flag.Flag) {
+		if f.Name == "o" || !set[f.Name] {
+			return
+		}
+		opts = append(opts, fmt.Sprintf("-%s=%s", f.Name, f.Value))
+	})
+	return strings.Join(opts, " ")
+}
+
+// setFlags returns the names of the flags of `flags` which determine the
+// options of an engine: all of them, unless an options file is given with
+// -opts, in which case only the flags given explicitly override the file.
+func setFlags(flags *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	visit := flags.VisitAll
+	if f := flags.Lookup("opts"); f != nil && f.Value.String() != "" {
+		visit = flags.Visit
+	}
+	visit(func(f *flag.Flag) { set[f.Name] = true })
+	return set
+}
+
+// stopGrace is the time given to an engine to stop after SIGINT or SIGTERM.
+// It is shorter than benchGrace, so that engines run by bench, run or hwmcc
+// store their results before they are killed.
+const stopGrace = 3 * time.Second
+
+// interrupt is the first SIGINT or SIGTERM received while an engine runs.
+var interrupt struct {
+	sync.Mutex
+	sig os.Signal
+}
+
+// interrupted returns whether an engine was interrupted by a signal.
+func interrupted() bool {
+	interrupt.Lock()
+	defer interrupt.Unlock()
+	return interrupt.sig != nil
+}
+
+// exitStatus returns the exit status of reach after an interrupt, 128 plus
+// the signal number as for shells, or 0 if there was no interrupt.
+func exitStatus() int {
+	interrupt.Lock()
+	defer interrupt.Unlock()
+	if interrupt.sig == nil {
+		return 0
+	}
+	if s, ok := interrupt.sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
+
+// signalStop stops an engine on SIGINT or SIGTERM.
+type signalStop struct {
+	mu   sync.Mutex
+	stop func()
+	ch   chan os.Signal
+	done chan struct{}
+}
+
+// stopOnSignal handles SIGINT and SIGTERM while an engine runs.  On the
+// first signal, the engine given to setStop is asked to stop, so that the
+// caller may fill and store the results known so far, and the caller
+// should not start other engines.  If the engine has not stopped
+// stopGrace later, or on a second signal, reach exits, keeping only the
+// results stored by engines as soon as they are found.  After an interrupt
+// reach exits with the status given by exitStatus.
+func stopOnSignal() *signalStop {
+	s := &signalStop{ch: make(chan os.Signal, 1), done: make(chan struct{})}
+	signal.Notify(s.ch, os.Interrupt, syscall.SIGTERM)
+	go s.run()
+	return s
+}
+
+func (s *signalStop) run() {
+	var grace <-chan time.Time
+	for {
+		select {
+		case sig := <-s.ch:
+			if grace != nil {
+				log.Printf("%s: exiting", sig)
+				os.Exit(exitStatus())
+			}
+			log.Printf("%s: stopping", sig)
+			interrupt.Lock()
+			interrupt.sig = sig
+			interrupt.Unlock()
+			s.mu.Lock()
+			if s.stop != nil {
+				s.stop()
+			}
+			s.mu.Unlock()
+			grace = time.After(stopGrace)
+		case <-grace:
+			log.Printf("engine did not stop within %s, exiting", stopGrace)
+			os.Exit(exitStatus())
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// setStop sets the function which stops the running engine, and calls it
+// if a signal was already received.
+func (s *signalStop) setStop(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop = f
+	if interrupted() {
+		f()
+	}
+}
+
+// close stops handling the signals.
+func (s *signalStop) close() {
+	signal.Stop(s.ch)
+	close(s.done)
+}
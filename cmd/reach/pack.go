@@ -0,0 +1,102 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-air/reach"
+)
+
+var packCmd = &subCmd{
+	Name:  "pack",
+	Flags: flag.NewFlagSet("pack", flag.ExitOnError),
+	Run:   doPack,
+	Init:  initPack,
+	Usage: "reach pack [opts] <output0> [<output1>, ...]",
+	Short: `pack archives output directories as single files.`,
+	Long: `
+pack writes each output directory as a single file archive <dir>.reach in the
+directory given by -o.  The archive is a gzip compressed tar file containing a
+copy of the aiger, the manifest and the traces, invariants and certificates of
+the results, so unlike an output directory it does not depend on a symlink to
+the aiger and may be moved between machines.
+
+Archives may be given in place of output directories to ck, cert, invmin,
+cexmin, stim, aag, aig, vcd and info.  Results stored by cert, invmin and
+cexmin are written back to the archive.
+
+If any output fails to be archived, reach exits with status 1.
+`}
+
+var packOpts = struct {
+	Dir *string
+	Rm  *bool
+}{}
+
+func initPack(cmd *subCmd) {
+	flags := cmd.Flags
+	packOpts.Dir = flags.String("o", ".", "directory for archives.")
+	packOpts.Rm = flags.Bool("rm", false, "remove output directories once archived.")
+	flags.Usage = func() {
+		fmt.Println(cmd.Usage)
+		flags.PrintDefaults()
+		fmt.Println(cmd.Long)
+	}
+}
+
+func doPack(cmd *subCmd, args []string) {
+	flags := cmd.Flags
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "no output directories specified.\n")
+		return
+	}
+	hasErr := false
+	for _, arg := range flags.Args() {
+		if err := doPackArg(arg); err != nil {
+			fmt.Printf("error packing '%s': %s\n", arg, err)
+			hasErr = true
+		}
+	}
+	if hasErr {
+		os.Exit(1)
+	}
+}
+
+func doPackArg(arg string) error {
+	if reach.IsArchive(arg) {
+		return fmt.Errorf("already an archive")
+	}
+	out, err := reach.OpenOutput(arg)
+	if err != nil {
+		return err
+	}
+	p := filepath.Join(*packOpts.Dir, filepath.Base(filepath.Clean(arg))+reach.ArchiveExt)
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = out.WriteArchive(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(p)
+		return err
+	}
+	fmt.Printf("packed %s to %s\n", arg, p)
+	if *packOpts.Rm {
+		return out.Remove()
+	}
+	return nil
+}
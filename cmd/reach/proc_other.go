@@ -0,0 +1,28 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// setMemLimit is not supported on this platform.
+func setMemLimit(mb int64) error {
+	return errors.New("memory limits are not supported on this platform")
+}
+
+// maxRSS is not available on this platform, and returns 0.
+func maxRSS(ps *os.ProcessState) int64 {
+	return 0
+}
+
+// terminate kills process `p`, as it cannot be signalled to stop on this
+// platform.
+func terminate(p *os.Process) error {
+	return p.Kill()
+}
@@ -0,0 +1,39 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package main
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// setMemLimit limits the address space of the process to `mb` megabytes.
+func setMemLimit(mb int64) error {
+	n := uint64(mb) << 20
+	return syscall.Setrlimit(syscall.RLIMIT_AS, &syscall.Rlimit{Cur: n, Max: n})
+}
+
+// maxRSS returns the maximum resident set size of the exited process `ps`,
+// in kilobytes.
+func maxRSS(ps *os.ProcessState) int64 {
+	ru, ok := ps.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	rss := int64(ru.Maxrss)
+	if runtime.GOOS == "darwin" {
+		// darwin reports bytes, the other systems kilobytes.
+		rss /= 1024
+	}
+	return rss
+}
+
+// terminate asks process `p` to stop, so that it may store its results.
+func terminate(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
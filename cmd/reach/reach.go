@@ -10,6 +10,8 @@ import (
 	"log"
 	"os"
 	"runtime/pprof"
+
+	"github.com/go-air/reach"
 )
 
 var outDir = "."
@@ -19,9 +21,20 @@ var subCmds = [...]*subCmd{
 	bmcCmd,
 	simCmd,
 	ckCmd,
+	certCmd,
+	invminCmd,
+	cexminCmd,
 	stimCmd,
 	aagCmd,
 	aigCmd,
+	vcdCmd,
+	packCmd,
+	reportCmd,
+	diffCmd,
+	benchCmd,
+	tuneCmd,
+	runCmd,
+	hwmccCmd,
 	infoCmd}
 
 // returns global argument list
@@ -46,6 +59,9 @@ func splitArgs(args []string) ([]string, []string) {
 
 var reachFlags = flag.NewFlagSet("reach", flag.ExitOnError)
 var pprofAddr = reachFlags.String("cpuprof", "", "file to output cpu profile")
+var traceFmtName = reachFlags.String("trace", "bin", "format of stored traces (bin, json, text)")
+var mergeOut = reachFlags.Bool("merge", false, "merge results into existing output directories")
+var traceFmt reach.TraceFormat
 
 var doc = `Reach is a finite state reachability tool for binary systems.
 
@@ -60,6 +76,7 @@ func usage(w io.Writer) {
 	fmt.Fprintf(w, "\nglobal options:\n")
 	reachFlags.SetOutput(w)
 	reachFlags.PrintDefaults()
+	fmt.Fprintf(w, "\n%s\n", jsonDoc)
 	fmt.Fprintf(w, "\nFor help on a command, try \"reach <cmd> -h\".\n")
 }
 
@@ -76,11 +93,23 @@ func main() {
 	reachFlags.Usage = func() {
 		usage(os.Stderr)
 	}
+	gargs, mb, err := parseMemLimit(gargs)
+	if err == nil && mb > 0 {
+		err = setMemLimit(mb)
+	}
+	if err != nil {
+		log.Fatalf("memory limit: %s", err)
+	}
 	reachFlags.Parse(gargs)
 	if len(largs) == 0 {
 		usage(os.Stderr)
 		os.Exit(1)
 	}
+	tf, err := reach.ParseTraceFormat(*traceFmtName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	traceFmt = tf
 	if *pprofAddr != "" {
 		f, e := os.Create(*pprofAddr)
 		if e != nil {
@@ -104,5 +133,10 @@ func main() {
 		usage(os.Stderr)
 		os.Exit(1)
 	}
+	initEvents(theCmd)
 	theCmd.Run(theCmd, largs[1:])
+	if code := exitStatus(); code != 0 {
+		pprof.StopCPUProfile()
+		os.Exit(code)
+	}
 }
@@ -0,0 +1,506 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-air/reach"
+)
+
+var reportCmd = &subCmd{
+	Name:  "report",
+	Flags: flag.NewFlagSet("report", flag.ExitOnError),
+	Run:   doReport,
+	Init:  initReport,
+	Usage: "reach report [opts] <dir0> [<dir1>, ...]\n       reach report [opts] -cmp <old> <new>",
+	Short: `report tabulates the results in trees of output directories.`,
+	Long: `
+report walks the given directories to find reach output directories and
+archives, and writes a table of the results with the output, bad state,
+status, depth, duration, engine and verification status of each result,
+followed by totals and the number of bad states solved by each engine.
+
+-f selects the format: csv, md (Markdown) or html, a self-contained page.
+CSV output contains only the table of results, for further processing.  By
+default, the report is written to stdout.
+
+With -ck, each result is verified as by "reach ck", by -j workers with a
+time limit of -dur for each invariant, and report exits with status 1 if
+any result fails verification.  Otherwise the verification status is "-".
+
+With -cmp, report compares the results in two trees, such as the output
+roots of two runs over the same aigers.  Results are matched by their bad
+state and the path of their output directory or archive relative to the
+root of its tree, without the .reach extension.  Each result is marked as
+"lost" if it is solved only in <old>, "gained" if it is solved only in
+<new>, "contradiction" if the two disagree on reachability, and "slower" or
+"faster" if both are solved and the duration changed by more than the
+factor -slow and by at least 100ms.  The totals give the number solved and
+the total duration in each tree.  If there are any lost, slower or
+contradicting results, then report exits with status 1.
+
+report exits with status 2 if no directories are given, or if -cmp is not
+given two directories.
+`}
+
+var reportOpts = struct {
+	Format *string
+	Out    *string
+	Ck     *bool
+	Dur    *time.Duration
+	J      *int
+	Cmp    *bool
+	Slow   *float64
+}{}
+
+// reportMinDelta is the smallest change in duration reported by -cmp.
+const reportMinDelta = 100 * time.Millisecond
+
+func initReport(cmd *subCmd) {
+	flags := cmd.Flags
+	reportOpts.Format = flags.String("f", "md", "report format (csv, md, html).")
+	reportOpts.Out = flags.String("o", "", "output path.")
+	reportOpts.Ck = flags.Bool("ck", false, "verify results.")
+	reportOpts.Dur = flags.Duration("dur", 5*time.Second, "time limit for checking each invariant.")
+	reportOpts.J = flags.Int("j", 0, "number of concurrent verification workers (default number of CPUs).")
+	reportOpts.Cmp = flags.Bool("cmp", false, "compare the results in two trees.")
+	reportOpts.Slow = flags.Float64("slow", 1.5, "factor by which durations must change for -cmp.")
+	flags.Usage = func() {
+		fmt.Println(cmd.Usage)
+		flags.PrintDefaults()
+		fmt.Println(cmd.Long)
+	}
+}
+
+func doReport(cmd *subCmd, args []string) {
+	flags := cmd.Flags
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "no directories specified.\n")
+		os.Exit(2)
+	}
+	if *reportOpts.Cmp && flags.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "-cmp needs 2 directories.\n")
+		os.Exit(2)
+	}
+	var tables []*table
+	hasErr := false
+	if *reportOpts.Cmp {
+		old, oerr := loadReport(flags.Args()[:1])
+		cur, nerr := loadReport(flags.Args()[1:])
+		var nReg int
+		tables, nReg = compareTables(old, cur)
+		hasErr = oerr || nerr || nReg != 0
+	} else {
+		var es []*reportEntry
+		es, hasErr = loadReport(flags.Args())
+		tables = reportTables(es)
+	}
+	if err := writeReport(tables); err != nil {
+		log.Printf("error writing report: %s", err)
+		hasErr = true
+	}
+	if hasErr {
+		os.Exit(1)
+	}
+}
+
+// reportEntry is a result of an output directory in a report.
+type reportEntry struct {
+	name  string // path of the output relative to its tree.
+	res   *reach.Result
+	check string // verification status.
+}
+
+func (e *reportEntry) key() string {
+	return fmt.Sprintf("%s/%d", e.name, e.res.M)
+}
+
+// loadReport reads the results of the outputs in the trees `roots`,
+// verifying them if -ck is given.  It returns whether any output could not
+// be read or any result failed verification.
+func loadReport(roots []string) ([]*reportEntry, bool) {
+	hasErr := false
+	var es []*reportEntry
+	var tasks []*ckTask
+	var outs []*reach.Output
+	for _, root := range roots {
+		ps, err := findOutputs(root)
+		if err != nil {
+			log.Printf("error reading '%s': %s", root, err)
+			hasErr = true
+			continue
+		}
+		for _, p := range ps {
+			out, err := reach.OpenOutput(p)
+			if err != nil {
+				log.Printf("error opening '%s': %s", p, err)
+				hasErr = true
+				continue
+			}
+			outs = append(outs, out)
+			name, err := filepath.Rel(root, p)
+			if err != nil || name == "." {
+				name = filepath.Base(p)
+			}
+			name = strings.TrimSuffix(name, reach.ArchiveExt)
+			for i, bad := range out.Results() {
+				es = append(es, &reportEntry{name: name, res: bad, check: "-"})
+				tasks = append(tasks, &ckTask{dir: p, out: out, i: i, bad: bad, limit: *reportOpts.Dur})
+			}
+		}
+	}
+	if *reportOpts.Ck {
+		ckRun(tasks, numJobs(*reportOpts.J))
+		for i, t := range tasks {
+			es[i].check = t.status()
+			if len(t.errs) != 0 {
+				hasErr = true
+			}
+		}
+	}
+	for _, out := range outs {
+		out.Close()
+	}
+	return es, hasErr
+}
+
+// findOutputs finds the output directories and archives under `root`, in
+// lexical order.
+func findOutputs(root string) ([]string, error) {
+	var ps []string
+	err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			if filepath.Ext(p) == reach.ArchiveExt && reach.IsArchive(p) {
+				ps = append(ps, p)
+			}
+			return nil
+		}
+		if _, err := os.Lstat(filepath.Join(p, "aig")); err == nil {
+			ps = append(ps, p)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return ps, err
+}
+
+// table is a titled table of a report.
+type table struct {
+	Title string
+	Head  []string
+	Rows  []tableRow
+}
+
+// tableRow is a row of a table.  Rows with a Mark are highlighted.
+type tableRow struct {
+	Mark  string
+	Cells []string
+}
+
+func (t *table) add(mark string, cells ...string) {
+	t.Rows = append(t.Rows, tableRow{Mark: mark, Cells: cells})
+}
+
+func reportTables(es []*reportEntry) []*table {
+	res := &table{
+		Title: "Results",
+		Head:  []string{"output", "bad", "status", "depth", "time (s)", "engine", "check"}}
+	var nReach, nUnreach, nUnknown, nOk, nFail int
+	var ttl time.Duration
+	by := make(map[string]int)
+	for _, e := range es {
+		b := e.res
+		res.add("", e.name, fmt.Sprintf("%d", b.M), b.FormatStatus(), fmt.Sprintf("%d", b.Depth),
+			fmtSeconds(b.Dur), reportEngine(b), e.check)
+		ttl += b.Dur
+		switch {
+		case b.IsReachable():
+			nReach++
+		case b.IsUnreachable():
+			nUnreach++
+		default:
+			nUnknown++
+		}
+		if b.IsSolved() {
+			by[reportEngine(b)]++
+		}
+		switch e.check {
+		case "ok":
+			nOk++
+		case "FAIL":
+			nFail++
+		}
+	}
+	tot := &table{Title: "Totals", Head: []string{"", "count"}}
+	tot.add("", "results", fmt.Sprintf("%d", len(es)))
+	tot.add("", "reachable", fmt.Sprintf("%d", nReach))
+	tot.add("", "unreachable", fmt.Sprintf("%d", nUnreach))
+	tot.add("", "unknown", fmt.Sprintf("%d", nUnknown))
+	if *reportOpts.Ck {
+		tot.add("", "verified", fmt.Sprintf("%d", nOk))
+		mark := ""
+		if nFail != 0 {
+			mark = "fail"
+		}
+		tot.add(mark, "failed", fmt.Sprintf("%d", nFail))
+	}
+	tot.add("", "time (s)", fmtSeconds(ttl))
+	return []*table{res, tot, solvedBy(by)}
+}
+
+func solvedBy(by map[string]int) *table {
+	res := &table{Title: "Solved by", Head: []string{"engine", "solved"}}
+	var engines []string
+	for e := range by {
+		engines = append(engines, e)
+	}
+	sort.Strings(engines)
+	for _, e := range engines {
+		res.add("", e, fmt.Sprintf("%d", by[e]))
+	}
+	return res
+}
+
+// compareTables compares the results `old` and `cur`, returning the tables
+// of the comparison and the number of regressions.
+func compareTables(old, cur []*reportEntry) ([]*table, int) {
+	res := &table{
+		Title: "Comparison",
+		Head: []string{"output", "bad", "old status", "new status",
+			"old time (s)", "new time (s)", "change"}}
+	olds := make(map[string]*reportEntry, len(old))
+	var keys []string
+	for _, e := range old {
+		olds[e.key()] = e
+		keys = append(keys, e.key())
+	}
+	curs := make(map[string]*reportEntry, len(cur))
+	for _, e := range cur {
+		if _, ok := olds[e.key()]; !ok {
+			keys = append(keys, e.key())
+		}
+		curs[e.key()] = e
+	}
+	nReg := 0
+	var oSolved, cSolved int
+	var oTtl, cTtl time.Duration
+	oBy, cBy := make(map[string]int), make(map[string]int)
+	for _, k := range keys {
+		o, c := olds[k], curs[k]
+		var ob, cb *reach.Result
+		name, m := "", ""
+		if o != nil {
+			ob = o.res
+			name, m = o.name, fmt.Sprintf("%d", ob.M)
+			oTtl += ob.Dur
+			if ob.IsSolved() {
+				oSolved++
+				oBy[reportEngine(ob)]++
+			}
+		}
+		if c != nil {
+			cb = c.res
+			name, m = c.name, fmt.Sprintf("%d", cb.M)
+			cTtl += cb.Dur
+			if cb.IsSolved() {
+				cSolved++
+				cBy[reportEngine(cb)]++
+			}
+		}
+		change := compareResults(ob, cb)
+		switch change {
+		case "lost", "slower", "contradiction":
+			nReg++
+		}
+		res.add(change, name, m, cmpStatus(ob), cmpStatus(cb), cmpSeconds(ob), cmpSeconds(cb), change)
+	}
+	tot := &table{Title: "Totals", Head: []string{"", "old", "new"}}
+	tot.add("", "results", fmt.Sprintf("%d", len(old)), fmt.Sprintf("%d", len(cur)))
+	mark := ""
+	if cSolved < oSolved {
+		mark = "lost"
+	}
+	tot.add(mark, "solved", fmt.Sprintf("%d", oSolved), fmt.Sprintf("%d", cSolved))
+	mark = ""
+	if slower(oTtl, cTtl) {
+		mark = "slower"
+	}
+	tot.add(mark, "time (s)", fmtSeconds(oTtl), fmtSeconds(cTtl))
+	by := &table{Title: "Solved by", Head: []string{"engine", "old", "new"}}
+	all := make(map[string]int)
+	for e := range oBy {
+		all[e]++
+	}
+	for e := range cBy {
+		all[e]++
+	}
+	for _, row := range solvedBy(all).Rows {
+		e := row.Cells[0]
+		by.add("", e, fmt.Sprintf("%d", oBy[e]), fmt.Sprintf("%d", cBy[e]))
+	}
+	return []*table{res, tot, by}, nReg
+}
+
+// compareResults classifies the change from `o` to `c`, either of which may
+// be nil if missing.
+func compareResults(o, c *reach.Result) string {
+	oSolved := o != nil && o.IsSolved()
+	cSolved := c != nil && c.IsSolved()
+	switch {
+	case oSolved && cSolved && o.Status != c.Status:
+		return "contradiction"
+	case oSolved && !cSolved:
+		return "lost"
+	case !oSolved && cSolved:
+		return "gained"
+	case !oSolved:
+		return ""
+	}
+	switch {
+	case slower(o.Dur, c.Dur):
+		return "slower"
+	case slower(c.Dur, o.Dur):
+		return "faster"
+	}
+	return ""
+}
+
+// slower returns whether duration `b` is slower than `a` by the factor -slow
+// and by at least reportMinDelta.
+func slower(a, b time.Duration) bool {
+	return b-a >= reportMinDelta && float64(b) > *reportOpts.Slow*float64(a)
+}
+
+func cmpStatus(b *reach.Result) string {
+	if b == nil {
+		return "-"
+	}
+	return b.FormatStatus()
+}
+
+func cmpSeconds(b *reach.Result) string {
+	if b == nil {
+		return "-"
+	}
+	return fmtSeconds(b.Dur)
+}
+
+func reportEngine(b *reach.Result) string {
+	if b.Engine == "" {
+		return "-"
+	}
+	return b.Engine
+}
+
+func fmtSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func writeReport(tables []*table) error {
+	w := io.Writer(os.Stdout)
+	if *reportOpts.Out != "" && *reportOpts.Out != "-" {
+		f, err := os.Create(*reportOpts.Out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	switch *reportOpts.Format {
+	case "csv":
+		return writeReportCSV(w, tables[0])
+	case "md":
+		return writeReportMD(w, tables)
+	case "html":
+		return reportHTML.Execute(w, tables)
+	default:
+		return fmt.Errorf("unknown report format '%s'", *reportOpts.Format)
+	}
+}
+
+func writeReportCSV(w io.Writer, t *table) error {
+	cw := csv.NewWriter(w)
+	cw.Write(t.Head)
+	for _, row := range t.Rows {
+		cw.Write(row.Cells)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeReportMD(w io.Writer, tables []*table) error {
+	esc := strings.NewReplacer("|", "\\|")
+	row := func(cells []string) string {
+		for i, c := range cells {
+			cells[i] = esc.Replace(c)
+		}
+		return "| " + strings.Join(cells, " | ") + " |\n"
+	}
+	for i, t := range tables {
+		if i > 0 {
+			fmt.Fprintf(w, "\n")
+		}
+		fmt.Fprintf(w, "## %s\n\n", t.Title)
+		fmt.Fprint(w, row(append([]string(nil), t.Head...)))
+		sep := make([]string, len(t.Head))
+		for j := range sep {
+			sep[j] = "---"
+		}
+		fmt.Fprint(w, row(sep))
+		for _, r := range t.Rows {
+			cells := append([]string(nil), r.Cells...)
+			if r.Mark != "" {
+				for j := range cells {
+					if cells[j] != "" {
+						cells[j] = "**" + cells[j] + "**"
+					}
+				}
+			}
+			if _, err := fmt.Fprint(w, row(cells)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var reportHTML = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>reach report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }
+th { background: #eee; }
+tr.lost, tr.slower, tr.contradiction, tr.fail { background: #fcc; }
+tr.gained, tr.faster { background: #cfc; }
+</style>
+</head>
+<body>
+<h1>reach report</h1>
+{{range .}}<h2>{{.Title}}</h2>
+<table>
+<tr>{{range .Head}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr{{if .Mark}} class="{{.Mark}}"{{end}}>{{range .Cells}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,57 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-air/reach"
+)
+
+func TestSlower(t *testing.T) {
+	for _, tc := range []struct {
+		a, b time.Duration
+		exp  bool
+	}{
+		{time.Second, time.Second, false},
+		{time.Second, 2 * time.Second, true},
+		{2 * time.Second, time.Second, false},
+		{time.Second, 1400 * time.Millisecond, false},
+		{time.Second, 1600 * time.Millisecond, true},
+		// below reportMinDelta.
+		{10 * time.Millisecond, 50 * time.Millisecond, false},
+		{0, reportMinDelta, true},
+	} {
+		if got := slower(tc.a, tc.b); got != tc.exp {
+			t.Errorf("slower(%s, %s): got %t", tc.a, tc.b, got)
+		}
+	}
+}
+
+func TestCompareResults(t *testing.T) {
+	res := func(status int, dur time.Duration) *reach.Result {
+		return &reach.Result{Status: status, Dur: dur}
+	}
+	for _, tc := range []struct {
+		name string
+		o, c *reach.Result
+		exp  string
+	}{
+		{"both missing", nil, nil, ""},
+		{"both unknown", res(0, time.Second), res(0, time.Hour), ""},
+		{"gained", nil, res(1, time.Second), "gained"},
+		{"gained from unknown", res(0, time.Second), res(-1, time.Second), "gained"},
+		{"lost", res(1, time.Second), nil, "lost"},
+		{"lost to unknown", res(-1, time.Second), res(0, time.Second), "lost"},
+		{"contradiction", res(1, time.Second), res(-1, time.Second), "contradiction"},
+		{"same", res(1, time.Second), res(1, 1200*time.Millisecond), ""},
+		{"slower", res(-1, time.Second), res(-1, 2*time.Second), "slower"},
+		{"faster", res(-1, 2*time.Second), res(-1, time.Second), "faster"},
+	} {
+		if got := compareResults(tc.o, tc.c); got != tc.exp {
+			t.Errorf("%s: got %q, expected %q", tc.name, got, tc.exp)
+		}
+	}
+}
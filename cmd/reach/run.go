@@ -0,0 +1,552 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-air/gini/z"
+	"github.com/go-air/reach"
+	"github.com/go-air/reach/iic"
+	"github.com/go-air/reach/sim"
+)
+
+var runCmd = &subCmd{
+	Name:  "run",
+	Flags: flag.NewFlagSet("run", flag.ExitOnError),
+	Run:   doRun,
+	Init:  initRun,
+	Usage: "reach run [opts] <job.json>",
+	Short: `run runs the engines and post-steps of a job file.`,
+	Long: `
+run reads a job file, which describes the aigers to check, the engines to
+check them with and the steps to take with the results, so that verification
+jobs may be kept under version control.  A job file is json such as
+
+  {
+    "Aigers": ["models/*.aig", "more"],
+    "Props": [0, 2],
+    "Output": "out",
+    "J": 4,
+    "Time": "5m",
+    "Budget": "2h",
+    "Mem": 4096,
+    "Engines": [
+      {"Engine": "bmc", "Options": {"Duration": 10000000000, "MaxDepth": 50}},
+      {"Engine": "iic", "Options": {"Duration": 60000000000, "DeepObs": false}},
+      {"Engine": "sim", "Options": {"N": 8}, "Args": ["-v"]}
+    ],
+    "Post": [
+      {"Step": "ck", "Args": ["-j", "4"]},
+      {"Step": "cexmin", "Args": ["-dur", "1m"]},
+      {"Step": "report", "Args": ["-f", "html", "-o", "out/report.html"]}
+    ]
+  }
+
+Aigers lists aiger files, glob patterns and directories containing aigers.
+Props selects the bad states to check by index, all of them if absent.
+Output is the output root, in which the output of each aiger is stored in
+the same relative location as the aiger under the directory of the job file.
+Relative paths in the job file, including in Args, are relative to the
+directory of the job file, in which the engines and steps are run.
+
+The engines are run in order on each aiger, with J aigers at a time, each
+engine in a separate reach process as with "reach bench", merging their
+results into the output.  Once the selected bad states are solved, the
+remaining engines are skipped.  Time limits each engine process as -time
+does for bench, Mem limits its memory in megabytes, and Budget limits the
+whole job: once it is spent no more engines are started.  Time and Budget
+are durations such as "90s"; absent or zero, they do not limit.
+
+The Options of an engine are the json fields of iic.Options for iic, of
+sim.Options for sim, and Duration and MaxDepth for bmc, with durations in
+nanoseconds.  Fields which are absent take the defaults of the engine's
+command.  Args gives further flags of the engine's command.  The messages
+of the engines are written to <output>.log.
+
+The post-steps ck, invmin, cexmin, cert and pack are run once on all outputs
+of the job, and report on the output root, each with the given Args.  Steps
+are run in order after all engines.
+
+run exits with status 1 if the job file is invalid, if an engine fails
+other than by reaching its time limit, or if a post-step fails.
+`}
+
+var runOpts = struct {
+	Dry *bool
+}{}
+
+func initRun(cmd *subCmd) {
+	flags := cmd.Flags
+	runOpts.Dry = flags.Bool("n", false, "print the commands without running them.")
+	flags.Usage = func() {
+		fmt.Println(cmd.Usage)
+		flags.PrintDefaults()
+		fmt.Println(cmd.Long)
+	}
+}
+
+// runJob is a job file.
+type runJob struct {
+	Aigers  []string
+	Props   []int
+	Output  string
+	J       int
+	Time    runDuration
+	Budget  runDuration
+	Mem     int64
+	Engines []*runEngine
+	Post    []*runStep
+
+	dir string // directory of the job file.
+}
+
+// runEngine is an engine of a job.
+type runEngine struct {
+	Engine  string
+	Options json.RawMessage
+	Args    []string
+}
+
+// runStep is a post-step of a job.
+type runStep struct {
+	Step string
+	Args []string
+}
+
+// engineCmds are the commands of the engines, by name.
+var engineCmds = map[string]*subCmd{"iic": iicCmd, "bmc": bmcCmd, "sim": simCmd}
+
+// runBmcOptions are the options of bmc in a job.
+type runBmcOptions struct {
+	Duration time.Duration
+	MaxDepth int
+}
+
+// runDuration is a duration given in json either as a string, such as
+// "90s", or as a number of nanoseconds.
+type runDuration time.Duration
+
+func (d *runDuration) UnmarshalJSON(bs []byte) error {
+	var s string
+	if err := json.Unmarshal(bs, &s); err == nil {
+		v, err := time.ParseDuration(s)
+		*d = runDuration(v)
+		return err
+	}
+	var n int64
+	if err := json.Unmarshal(bs, &n); err != nil {
+		return fmt.Errorf("duration %s: %w", bs, err)
+	}
+	*d = runDuration(n)
+	return nil
+}
+
+func doRun(cmd *subCmd, args []string) {
+	flags := cmd.Flags
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.SetOutput(os.Stderr)
+		flags.Usage()
+		os.Exit(2)
+	}
+	job, err := readRunJob(flags.Arg(0))
+	if err != nil {
+		log.Fatalf("error reading job '%s': %s", flags.Arg(0), err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	aigs, err := job.aigers()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(aigs) == 0 {
+		log.Fatal("run: no aigers found")
+	}
+	tmp, err := ioutil.TempDir("", "reach-run")
+	if err != nil {
+		log.Fatal(err)
+	}
+	eargs := make([][]string, len(job.Engines))
+	for i, e := range job.Engines {
+		eargs[i], err = e.args(job.props(), 0, filepath.Join(tmp, fmt.Sprintf("%d-%s.json", i, e.Engine)))
+		if err != nil {
+			os.RemoveAll(tmp)
+			log.Fatalf("engine %d (%s): %s", i, e.Engine, err)
+		}
+	}
+	r := &runState{job: job, exe: exe, eargs: eargs}
+	if job.Budget > 0 {
+		r.deadline = time.Now().Add(time.Duration(job.Budget))
+	}
+	log.Printf("run: %d aigers, %d engines", len(aigs), len(job.Engines))
+	ch := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < job.J; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range ch {
+				r.aiger(p)
+			}
+		}()
+	}
+	for _, p := range aigs {
+		ch <- p
+	}
+	close(ch)
+	wg.Wait()
+	os.RemoveAll(tmp)
+	for _, s := range job.Post {
+		if !r.step(s) {
+			r.fail()
+		}
+	}
+	if r.failed {
+		os.Exit(1)
+	}
+}
+
+// readRunJob reads and checks the job file `p`.
+func readRunJob(p string) (*runJob, error) {
+	bs, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	job := &runJob{Output: ".", J: 1}
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(job); err != nil {
+		return nil, err
+	}
+	job.dir, err = filepath.Abs(filepath.Dir(p))
+	if err != nil {
+		return nil, err
+	}
+	job.Output = job.path(job.Output)
+	if len(job.Aigers) == 0 {
+		return nil, fmt.Errorf("no aigers")
+	}
+	if len(job.Engines) == 0 {
+		return nil, fmt.Errorf("no engines")
+	}
+	if job.J < 1 {
+		return nil, fmt.Errorf("J must be positive")
+	}
+	for _, e := range job.Engines {
+		if engineCmds[e.Engine] == nil {
+			return nil, fmt.Errorf("unknown engine '%s'", e.Engine)
+		}
+	}
+	for _, s := range job.Post {
+		switch s.Step {
+		case "ck", "invmin", "cexmin", "cert", "pack", "report":
+		default:
+			return nil, fmt.Errorf("unknown post-step '%s'", s.Step)
+		}
+	}
+	return job, nil
+}
+
+// path returns the path `p` of the job file as an absolute path.
+func (j *runJob) path(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(j.dir, p)
+}
+
+// aigers returns the aigers of the job.
+func (j *runJob) aigers() ([]string, error) {
+	var args []string
+	for _, a := range j.Aigers {
+		ps, err := filepath.Glob(j.path(a))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a, err)
+		}
+		if len(ps) == 0 {
+			return nil, fmt.Errorf("%s: no such aiger", a)
+		}
+		args = append(args, ps...)
+	}
+	fns, err := findAigers(args)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var res []string
+	for _, fn := range fns {
+		if !seen[fn] {
+			seen[fn] = true
+			res = append(res, fn)
+		}
+	}
+	return res, nil
+}
+
+// props returns the bad state selection of the job as given to -props.
+func (j *runJob) props() string {
+	var ss []string
+	for _, i := range j.Props {
+		ss = append(ss, strconv.Itoa(i))
+	}
+	return strings.Join(ss, ",")
+}
+
+// outputPath returns the path of the output directory of aiger `p`.
+func (j *runJob) outputPath(p string) string {
+	base := filepath.Base(p)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	rel, err := filepath.Rel(j.dir, filepath.Dir(p))
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = "."
+	}
+	return filepath.Join(j.Output, rel, base)
+}
+
+// args returns the arguments of the engine's command for the bad states
+// selected by `props`, writing its options to the file `opath` if needed.
+// The duration of the engine is `dur` unless given in its options, or the
+// default of its command if `dur` is 0.
+func (e *runEngine) args(props string, dur time.Duration, opath string) ([]string, error) {
+	var args []string
+	if dur == 0 {
+		dur = flagDuration(engineCmds[e.Engine], "dur")
+	}
+	switch e.Engine {
+	case "iic":
+		opts := iic.NewOptions()
+		opts.Duration = dur
+		if err := decodeOptions(e.Options, opts); err != nil {
+			return nil, err
+		}
+		if err := writeJSONFile(opath, opts); err != nil {
+			return nil, err
+		}
+		args = append(args, "-opts", opath)
+	case "sim":
+		opts := sim.NewOptions()
+		opts.Duration = dur
+		if err := decodeOptions(e.Options, opts); err != nil {
+			return nil, err
+		}
+		if err := writeJSONFile(opath, opts); err != nil {
+			return nil, err
+		}
+		args = append(args, "-opts", opath)
+	case "bmc":
+		opts := &runBmcOptions{Duration: dur, MaxDepth: *bmcOpts.MaxDepth}
+		if err := decodeOptions(e.Options, opts); err != nil {
+			return nil, err
+		}
+		args = append(args, "-dur", opts.Duration.String(), "-to", strconv.Itoa(opts.MaxDepth))
+	}
+	if props != "" {
+		args = append(args, "-props", props)
+	}
+	return append(args, e.Args...), nil
+}
+
+// decodeOptions decodes the json options `raw`, if any, into `opts`.
+func decodeOptions(raw json.RawMessage, opts interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(opts); err != nil {
+		return fmt.Errorf("options: %w", err)
+	}
+	return nil
+}
+
+// flagDuration returns the default of the duration flag `name` of `cmd`.
+func flagDuration(cmd *subCmd, name string) time.Duration {
+	d, _ := time.ParseDuration(cmd.Flags.Lookup(name).DefValue)
+	return d
+}
+
+// runState is the state of a running job.
+type runState struct {
+	job      *runJob
+	exe      string
+	eargs    [][]string
+	deadline time.Time
+
+	mu      sync.Mutex
+	outputs []string
+	failed  bool
+}
+
+func (r *runState) fail() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failed = true
+}
+
+// limit returns the time limit of an engine process and whether there is
+// any time left in the budget.
+func (r *runState) limit() (time.Duration, bool) {
+	lim := time.Duration(r.job.Time)
+	if r.deadline.IsZero() {
+		return lim, true
+	}
+	left := time.Until(r.deadline)
+	if left <= 0 {
+		return 0, false
+	}
+	if lim <= 0 || lim > left {
+		lim = left
+	}
+	return lim, true
+}
+
+// aiger runs the engines of the job on aiger `p`.
+func (r *runState) aiger(p string) {
+	opath := r.job.outputPath(p)
+	odir := filepath.Dir(opath)
+	bad, err := runBad(p, r.job.props())
+	if err != nil {
+		log.Printf("run: %s: %s", p, err)
+		r.fail()
+		return
+	}
+	if err := os.MkdirAll(odir, 0755); err != nil {
+		log.Printf("run: %s: %s", p, err)
+		r.fail()
+		return
+	}
+	for i, e := range r.job.Engines {
+		lim, ok := r.limit()
+		if !ok {
+			log.Printf("run: %s: budget spent", p)
+			break
+		}
+		args := append(memLimitArgs(r.job.Mem), "-merge", "-trace", *traceFmtName, e.Engine)
+		args = append(args, r.eargs[i]...)
+		args = append(args, "-o", odir, p)
+		if *runOpts.Dry {
+			fmt.Println(r.exe, strings.Join(args, " "))
+			continue
+		}
+		if !r.engine(p, e.Engine, opath, args, lim) {
+			r.fail()
+		}
+		if runSolved(opath, bad) {
+			break
+		}
+	}
+	r.mu.Lock()
+	r.outputs = append(r.outputs, opath)
+	r.mu.Unlock()
+}
+
+// engine runs the reach process with arguments `args` for engine `name`
+// on aiger `p`, and returns whether it succeeded or reached its time limit.
+func (r *runState) engine(p, name, opath string, args []string, lim time.Duration) bool {
+	c := exec.Command(r.exe, args...)
+	c.Dir = r.job.dir
+	logf, err := os.OpenFile(opath+".log", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		log.Printf("run: %s: %s", p, err)
+		return false
+	}
+	defer logf.Close()
+	fmt.Fprintf(logf, "# reach %s\n", strings.Join(args, " "))
+	c.Stdout, c.Stderr = logf, logf
+	start := time.Now()
+	timeout, err := runLimited(c, lim)
+	dur := time.Since(start).Round(time.Millisecond)
+	switch {
+	case c.ProcessState == nil:
+		log.Printf("run: %s %s: %s", p, name, err)
+		return false
+	case timeout:
+		log.Printf("run: %s %s: time limit reached after %s", p, name, dur)
+		return true
+	case err != nil:
+		log.Printf("run: %s %s: %s after %s, see %s.log", p, name, err, dur, opath)
+		return false
+	}
+	log.Printf("run: %s %s: done in %s", p, name, dur)
+	return true
+}
+
+// step runs the post-step `s` and returns whether it succeeded.
+func (r *runState) step(s *runStep) bool {
+	args := append([]string{s.Step}, s.Args...)
+	if s.Step == "report" {
+		args = append(args, r.job.Output)
+	} else {
+		var outs []string
+		for _, p := range r.outputs {
+			if _, err := os.Stat(p); err == nil || *runOpts.Dry {
+				outs = append(outs, p)
+			}
+		}
+		if len(outs) == 0 {
+			log.Printf("run: %s: no outputs", s.Step)
+			return false
+		}
+		args = append(args, outs...)
+	}
+	if *runOpts.Dry {
+		fmt.Println(r.exe, strings.Join(args, " "))
+		return true
+	}
+	log.Printf("run: %s", s.Step)
+	c := exec.Command(r.exe, args...)
+	c.Dir = r.job.dir
+	c.Stdout, c.Stderr = os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		log.Printf("run: %s: %s", s.Step, err)
+		return false
+	}
+	return true
+}
+
+// runBad returns the selected bad states of aiger `p`.
+func runBad(p, props string) ([]z.Lit, error) {
+	aig, err := readAiger(p)
+	if err != nil {
+		return nil, err
+	}
+	return selectBad(aigerBad(aig), props)
+}
+
+// runSolved returns whether the bad states `bad` are solved in the output
+// `opath`.
+func runSolved(opath string, bad []z.Lit) bool {
+	out, err := reach.OpenOutput(opath)
+	if err != nil {
+		return false
+	}
+	defer out.Close()
+	solved := make(map[z.Lit]bool)
+	for _, b := range out.Results() {
+		if b.IsSolved() {
+			solved[b.M] = true
+		}
+	}
+	for _, m := range bad {
+		if !solved[m] {
+			return false
+		}
+	}
+	return true
+}
@@ -10,7 +10,6 @@ import (
 	"os"
 	"time"
 
-	"github.com/go-air/reach"
 	"github.com/go-air/reach/sim"
 )
 
@@ -31,6 +30,15 @@ leading to the bad state.
 
 Reachable bad states have 'Depth' reported as the true number of steps, which
 may exceed the trace memory limit.
+
+Reached bad states are stored as soon as they are first reached.  If reach
+receives SIGINT or SIGTERM, sim stops after the current step and stores its
+results, without simulating further aigers.  On a second signal, reach exits
+keeping only the results already stored.  After a signal, reach exits with
+status 128 plus the signal number: 130 for SIGINT and 143 for SIGTERM.
+
+-opts reads the options from a json file of sim.Options.  Flags given
+explicitly override the options in the file.
 `}
 
 var simOpts = struct {
@@ -43,6 +51,8 @@ var simOpts = struct {
 	Verbose       *bool
 	N             *int
 	Seed          *int64
+	Props         *string
+	OptsFile      *string
 }{}
 
 var untilDoc = `"-until n" will limit sim so that it runs at most
@@ -59,6 +69,8 @@ func initSim(cmd *subCmd) {
 	simOpts.RestartFactor = flags.Int("restart", 0, "restart factor for Luby series restarts (default 0).")
 	simOpts.Seed = flags.Int64("seed", 44, "random seed.")
 	simOpts.Verbose = flags.Bool("v", false, "verbosity.")
+	simOpts.Props = flags.String("props", "", propsDoc)
+	simOpts.OptsFile = flags.String("opts", "", "json options file of sim.Options.")
 	flags.StringVar(&outDir, "o", ".", "output directory")
 
 	flags.Usage = func() {
@@ -74,45 +86,96 @@ func doSim(cmd *subCmd, args []string) {
 	if flags.NArg() == 0 {
 		fmt.Fprintf(os.Stderr, "no aigs specified.\n")
 	}
-	for i := 0; i < flags.NArg(); i++ {
-		if err := doSimArg(flags.Arg(i)); err != nil {
+	for i := 0; i < flags.NArg() && !interrupted(); i++ {
+		if err := doSimArg(cmd, flags.Arg(i)); err != nil {
 			log.Printf("%s", err)
+			emitError(cmd, flags.Arg(i), true, err)
 		}
 	}
 }
 
-func doSimArg(fn string) error {
-	deadLine := time.Now().Add(*simOpts.Dur)
+func doSimArg(cmd *subCmd, fn string) error {
+	start := time.Now()
 	aig, err := readAiger(fn)
 	if err != nil {
 		return err
 	}
-	bad := aigerBad(aig)
+	bad, err := selectBad(aigerBad(aig), *simOpts.Props)
+	if err != nil {
+		return err
+	}
 	if len(bad) == 0 {
 		return fmt.Errorf("ErrNoBads")
 	}
-	opts := sim.NewOptions()
-	opts.WatchUntil = *simOpts.MaxWatchCount
-	opts.MaxDepth = int64(*simOpts.MaxDepth)
-	opts.Duration = time.Until(deadLine)
-	opts.Verbose = *simOpts.Verbose
-	opts.N = *simOpts.N
-	opts.RestartFactor = *simOpts.RestartFactor
-	opts.Seed = *simOpts.Seed
+	opts, err := simOptions(cmd.Flags)
+	if err != nil {
+		return err
+	}
+	opts.Duration -= time.Since(start)
 
+	out, err := makeOutput(cmd, fn)
+	if err != nil {
+		return err
+	}
+	sigs := stopOnSignal()
+	defer sigs.close()
+	emit(&event{Event: "start", Cmd: cmd.Name, Aiger: fn, Output: out.RootDir(), Options: flagOptions(cmd.Flags)})
+	if *jsonEvents {
+		opts.Observer = &simProgress{cmd: cmd, fn: fn}
+		opts.StepEvery = jsonStepEvery
+	}
 	ck := sim.New(aig.Sys(), bad...)
 	ck.SetOptions(opts)
+	ck.SetOutput(out)
+	sigs.setStop(ck.Stop)
 	n := ck.Simulate()
 	if opts.Verbose {
 		fmt.Printf("[sim] did %d steps for 64 traces\n", n)
 	}
-	out, err := reach.MakeOutput(fn, outDir)
-	if err != nil {
-		return err
+	if err := ck.Err(); err != nil {
+		log.Printf("%s: %s", fn, err)
+		emitError(cmd, fn, true, err)
 	}
 	ck.FillOutput(out)
+	err = out.Store()
+	emitResults(cmd, fn, out, bad)
 	for _, b := range out.Results() {
 		fmt.Printf("\t%s\n", b)
 	}
-	return out.Store()
+	return err
+}
+
+// simOptions returns the sim options given by `flags`.  With -opts, the
+// options are read from the file and only flags which are set override
+// them.
+func simOptions(flags *flag.FlagSet) (*sim.Options, error) {
+	opts := sim.NewOptions()
+	if *simOpts.OptsFile != "" {
+		if err := readJSONFile(*simOpts.OptsFile, opts); err != nil {
+			return nil, fmt.Errorf("reading options: %w", err)
+		}
+	}
+	set := setFlags(flags)
+	if set["until"] {
+		opts.WatchUntil = *simOpts.MaxWatchCount
+	}
+	if set["to"] {
+		opts.MaxDepth = *simOpts.MaxDepth
+	}
+	if set["dur"] {
+		opts.Duration = *simOpts.Dur
+	}
+	if set["v"] {
+		opts.Verbose = *simOpts.Verbose
+	}
+	if set["n"] {
+		opts.N = *simOpts.N
+	}
+	if set["restart"] {
+		opts.RestartFactor = *simOpts.RestartFactor
+	}
+	if set["seed"] {
+		opts.Seed = *simOpts.Seed
+	}
+	return opts, nil
 }
@@ -11,6 +11,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/go-air/gini/logic/aiger"
+	"github.com/go-air/gini/z"
 	"github.com/go-air/reach"
 )
 
@@ -25,16 +27,21 @@ var stimCmd = &subCmd{
 stim output saiger stimuli from an output directory.  The output 
 directory should have a .trace file associated with a bad state.
 
+With -witness, stim outputs aiger 1.9 (HWMCC) witnesses, which include
+the property and the initial latch values, instead of stimuli.
+
 By default, the output is written to stdout.
 `}
 
 var stimOpts = struct {
 	outPathSuffix *string
+	witness       *bool
 }{}
 
 func initStim(cmd *subCmd) {
 	flags := cmd.Flags
 	stimOpts.outPathSuffix = flags.String("o", "", "suffix (after bad.) for aiger stimuli output files.")
+	stimOpts.witness = flags.Bool("witness", false, "output aiger 1.9 witnesses.")
 	flags.Usage = func() {
 		fmt.Println(cmd.Usage)
 		flags.PrintDefaults()
@@ -50,6 +57,7 @@ func doStim(cmd *subCmd, args []string) {
 	}
 	if err := doStimArg(cmd, flags.Arg(0)); err != nil {
 		log.Printf("error writing stimulus for '%s': %s\n", flags.Arg(0), err.Error())
+		emitError(cmd, flags.Arg(0), false, err)
 	}
 }
 
@@ -59,54 +67,92 @@ func doStimArg(cmd *subCmd, arg string) error {
 	if os.IsNotExist(err) {
 		return err
 	}
-	if !st.IsDir() {
+	if !st.IsDir() && !reach.IsArchive(arg) {
 		flags.SetOutput(os.Stderr)
 		flags.Usage()
 		fmt.Fprintf(os.Stderr, `cannot output stimulus, need reach output dir with a trace.\n`)
 		os.Exit(2)
 	}
-	return doStimOutput(arg)
+	return doStimOutput(cmd, arg)
 }
 
-func doStimOutput(arg string) error {
+func doStimOutput(cmd *subCmd, arg string) error {
 	out, err := reach.OpenOutput(arg)
 	if err != nil {
 		return err
 	}
+	defer out.Close()
+	emit(&event{Event: "start", Cmd: cmd.Name, Output: arg})
+	var aig *aiger.T
+	if *stimOpts.witness {
+		aig, err = out.Aiger()
+		if err != nil {
+			return err
+		}
+	}
 	for i, b := range out.Results() {
 		if *stimOpts.outPathSuffix != "-" && *stimOpts.outPathSuffix != "" {
 			fmt.Printf("getting stimulus for %s...", b)
 		}
-		w, err := stimWriter(b)
+		prop := 0
+		if aig != nil {
+			var err error
+			prop, err = badIndex(aig, b.M)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error writing witness: %s\n", err.Error())
+				emitError(cmd, arg, false, err)
+				continue
+			}
+		}
+		e := &event{Event: "result", Cmd: cmd.Name, Output: arg, Result: b}
+		w, err := stimWriter(e)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error opening stim output: %s\n", err.Error())
+			emitError(cmd, arg, false, err)
 			continue
 		}
 		trace, err := out.Trace(i)
 		if err != nil {
-			w.Close()
+			if w != os.Stdout {
+				w.Close()
+			}
 			fmt.Fprintf(os.Stderr, "error reading trace: %s\n", err.Error())
+			emitError(cmd, arg, false, err)
 			continue
 		}
-		_, err = trace.EncodeAigerStim(w)
+		if aig != nil {
+			err = trace.EncodeAigerWitness(w, aig, prop)
+		} else {
+			_, err = trace.EncodeAigerStim(w)
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error writing stim: %s\n", err.Error())
+			emitError(cmd, arg, false, err)
 		}
 		if w != os.Stdout {
 			w.Close()
 		}
 		if *stimOpts.outPathSuffix != "-" && *stimOpts.outPathSuffix != "" {
 			fmt.Printf("wrote output to %s.\n", stimOutPath(b))
+			e.File = stimOutPath(b)
+			emit(e)
 		}
 	}
 	return nil
 }
 
-func stimWriter(bad *reach.Result) (io.WriteCloser, error) {
-	var f *os.File
+// stimWriter opens the stimulus output for the result of event `e`.  With
+// -json, the stimulus which would be written to stdout is emitted with `e`
+// when the output is closed.
+func stimWriter(e *event) (io.WriteCloser, error) {
+	bad := e.Result
+	var f io.WriteCloser
 	var err error
 	if *stimOpts.outPathSuffix == "-" || *stimOpts.outPathSuffix == "" {
 		f = os.Stdout
+		if *jsonEvents {
+			f = &eventWriter{e: e}
+		}
 	} else {
 		pathName := stimOutPath(bad)
 		f, err = os.OpenFile(pathName, os.O_WRONLY|os.O_CREATE, 0644)
@@ -114,12 +160,26 @@ func stimWriter(bad *reach.Result) (io.WriteCloser, error) {
 			return nil, err
 		}
 	}
+	if *stimOpts.witness {
+		return f, nil
+	}
 	if _, err := fmt.Fprintf(f, "c (aiger) trace for %s\n", bad); err != nil {
 		return nil, err
 	}
 	return f, nil
 }
 
+// badIndex gives the index of `m` in the bad states of `g`, or an error if
+// `m` is not a bad state of `g`.
+func badIndex(g *aiger.T, m z.Lit) (int, error) {
+	for i, b := range aigerBad(g) {
+		if b == m {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%s is not a bad state of the aiger", m)
+}
+
 func stimOutPath(b *reach.Result) string {
 	dir, nm := filepath.Split(*stimOpts.outPathSuffix)
 
@@ -0,0 +1,410 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/z"
+	"github.com/go-air/reach"
+	"github.com/go-air/reach/iic"
+)
+
+var tuneCmd = &subCmd{
+	Name:  "tune",
+	Flags: flag.NewFlagSet("tune", flag.ExitOnError),
+	Run:   doTune,
+	Init:  initTune,
+	Usage: "reach tune [opts] <aiger | dir> [<aiger | dir>, ...]",
+	Short: `tune searches for the best iic options on a set of aigers.`,
+	Long: `
+tune searches the combinations of the iic switches Preprocess, FilterObs,
+ConsecuSift, ConsecuSiftPull, Justify, DeepObs and GnrlRemoveLits for the
+configuration which solves the bad states of the given aigers (or the aigers
+under the given directories) fastest.  The compile time constants in iic/cfg.go
+are not tuned.
+
+The configurations, at most -n of them chosen at random besides the defaults,
+race over the bad states in random order.  Each configuration runs iic on
+each bad state with a time limit of -dur, and costs the time taken if it
+solves the bad state, or twice -dur if it does not.  After the first 3 bad
+states, configurations are discarded if they cost both more than -factor
+times the best configuration so far and more than 100ms above it.  The race
+stops when all bad states have been raced or the time budget -budget is
+spent.  No run exceeds the time left in the budget, and a bad state on which
+the budget runs out is not counted.
+
+If configurations disagree on whether a bad state is reachable, their traces
+and invariants are verified, and configurations whose results do not verify
+within -dur are discarded.
+
+The best remaining configuration is written as a json file of iic.Options to
+-o, which may be given to "reach iic -opts".  Its Duration is the default of
+iic.Options, not -dur; "reach iic" takes its time limit from its own -dur.  A table of the remaining
+configurations is printed, in which each configuration is shown with a bit
+for each switch, in the order above.  -j configurations are run at a time;
+as they compete for the machine, timings are most reliable with -j 1.
+`}
+
+var tuneOpts = struct {
+	Dur    *time.Duration
+	Budget *time.Duration
+	N      *int
+	J      *int
+	Factor *float64
+	Seed   *int64
+	Out    *string
+}{}
+
+// tuneMinRounds is the number of bad states raced before discarding
+// configurations.
+const tuneMinRounds = 3
+
+// tuneMinDelta is the smallest difference in cost for which configurations
+// are discarded.
+const tuneMinDelta = 100 * time.Millisecond
+
+func initTune(cmd *subCmd) {
+	flags := cmd.Flags
+	tuneOpts.Dur = flags.Duration("dur", 10*time.Second, "time limit for each run.")
+	tuneOpts.Budget = flags.Duration("budget", 10*time.Minute, "time budget for tuning.")
+	tuneOpts.N = flags.Int("n", 32, "maximum number of configurations.")
+	tuneOpts.J = flags.Int("j", 1, "number of concurrent runs.")
+	tuneOpts.Factor = flags.Float64("factor", 1.5, "cost factor for discarding configurations.")
+	tuneOpts.Seed = flags.Int64("seed", 44, "random seed.")
+	tuneOpts.Out = flags.String("o", "iic-opts.json", "options file for the best configuration.")
+	flags.Usage = func() {
+		fmt.Println(cmd.Usage)
+		flags.PrintDefaults()
+		fmt.Println(cmd.Long)
+	}
+}
+
+// tuneSwitches are the tuned switches of iic.Options.
+var tuneSwitches = []struct {
+	name string
+	get  func(o *iic.Options) *bool
+}{
+	{"Preprocess", func(o *iic.Options) *bool { return &o.Preprocess }},
+	{"FilterObs", func(o *iic.Options) *bool { return &o.FilterObs }},
+	{"ConsecuSift", func(o *iic.Options) *bool { return &o.ConsecuSift }},
+	{"ConsecuSiftPull", func(o *iic.Options) *bool { return &o.ConsecuSiftPull }},
+	{"Justify", func(o *iic.Options) *bool { return &o.Justify }},
+	{"DeepObs", func(o *iic.Options) *bool { return &o.DeepObs }},
+	{"GnrlRemoveLits", func(o *iic.Options) *bool { return &o.GnrlRemoveLits }},
+}
+
+// tuneConfig is a configuration in the race.
+type tuneConfig struct {
+	opts   *iic.Options
+	cost   time.Duration
+	solved int
+	runs   int
+}
+
+func (c *tuneConfig) String() string {
+	s := ""
+	for _, sw := range tuneSwitches {
+		if *sw.get(c.opts) {
+			s += "1"
+		} else {
+			s += "0"
+		}
+	}
+	return s
+}
+
+// tuneProblem is a bad state of an aiger.
+type tuneProblem struct {
+	name string
+	sys  *logic.S
+	bad  z.Lit
+}
+
+func doTune(cmd *subCmd, args []string) {
+	flags := cmd.Flags
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "no aigs specified.\n")
+		return
+	}
+	deadline := time.Now().Add(*tuneOpts.Budget)
+	rnd := rand.New(rand.NewSource(*tuneOpts.Seed))
+	probs, err := tuneProblems(flags.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(probs) == 0 {
+		log.Fatal("no bad states to tune on")
+	}
+	rnd.Shuffle(len(probs), func(i, j int) { probs[i], probs[j] = probs[j], probs[i] })
+	cfgs := tuneConfigs(rnd, *tuneOpts.N)
+	log.Printf("tune: %d configurations on %d bad states", len(cfgs), len(probs))
+	var names []string
+	for _, sw := range tuneSwitches {
+		names = append(names, sw.name)
+	}
+	log.Printf("tune: configuration bits are %s", strings.Join(names, ", "))
+	for r, p := range probs {
+		if time.Now().After(deadline) {
+			log.Printf("tune: budget spent after %d bad states", r)
+			break
+		}
+		var ok bool
+		cfgs, ok = tuneRound(cfgs, p, deadline)
+		if !ok {
+			log.Printf("tune: budget spent during bad state %d, which is not counted", r+1)
+			break
+		}
+		if len(cfgs) == 0 {
+			log.Fatalf("tune: %s: no configuration verified", p.name)
+		}
+		if r+1 >= tuneMinRounds {
+			cfgs = tuneDiscard(cfgs)
+		}
+		log.Printf("tune: %d/%d %s: %d configurations remain, best %s", r+1, len(probs), p.name,
+			len(cfgs), tuneBest(cfgs))
+	}
+	sort.SliceStable(cfgs, func(i, j int) bool { return cfgs[i].cost < cfgs[j].cost })
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "config\tsolved\truns\tcost\n")
+	for _, c := range cfgs {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", c, c.solved, c.runs, c.cost.Round(time.Millisecond))
+	}
+	tw.Flush()
+	best := tuneBest(cfgs)
+	opts := *best.opts
+	opts.Duration = iic.NewOptions().Duration
+	if err := writeJSONFile(*tuneOpts.Out, &opts); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("wrote best configuration %s to %s\n", best, *tuneOpts.Out)
+}
+
+// tuneProblems reads the bad states of the aigers given by `args`, which may
+// be directories containing aigers.
+func tuneProblems(args []string) ([]*tuneProblem, error) {
+	fns, err := findAigers(args)
+	if err != nil {
+		return nil, err
+	}
+	var res []*tuneProblem
+	for _, fn := range fns {
+		aig, err := readAiger(fn)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fn, err)
+		}
+		for i, b := range aigerBad(aig) {
+			res = append(res, &tuneProblem{name: fmt.Sprintf("%s[%d]", fn, i), sys: aig.S, bad: b})
+		}
+	}
+	return res, nil
+}
+
+// tuneConfigs returns the default configuration and up to n-1 others
+// chosen at random.
+func tuneConfigs(rnd *rand.Rand, n int) []*tuneConfig {
+	N := 1 << uint(len(tuneSwitches))
+	def := iic.NewOptions()
+	def.Duration = *tuneOpts.Dur
+	cfgs := []*tuneConfig{{opts: def}}
+	seen := map[string]bool{cfgs[0].String(): true}
+	for _, k := range rnd.Perm(N) {
+		if len(cfgs) >= n {
+			break
+		}
+		opts := *def
+		for i, sw := range tuneSwitches {
+			*sw.get(&opts) = k&(1<<uint(i)) != 0
+		}
+		c := &tuneConfig{opts: &opts}
+		if seen[c.String()] {
+			continue
+		}
+		seen[c.String()] = true
+		cfgs = append(cfgs, c)
+	}
+	return cfgs
+}
+
+// tuneRun is the result of running a configuration on a bad state.
+type tuneRun struct {
+	c   *tuneConfig
+	mc  *iic.T
+	res int
+	dur time.Duration
+}
+
+// tuneRound runs each configuration on `p`, -j at a time, and adds the
+// costs of the runs to the configurations.  Each run is limited to the time
+// left before `deadline`.  If the deadline cuts a run short or leaves
+// configurations unrun, no costs are added and tuneRound returns false.
+//
+// If the runs disagree on reachability, the configurations whose results
+// do not verify are removed.  tuneRound returns the remaining
+// configurations.
+func tuneRound(cfgs []*tuneConfig, p *tuneProblem, deadline time.Time) ([]*tuneConfig, bool) {
+	ch := make(chan *tuneConfig)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var runs []tuneRun
+	cut := false
+	for w := 0; w < *tuneOpts.J; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range ch {
+				opts := *c.opts
+				left := time.Until(deadline)
+				capped := left < opts.Duration
+				if capped {
+					opts.Duration = left
+				}
+				if left <= 0 {
+					mu.Lock()
+					cut = true
+					mu.Unlock()
+					continue
+				}
+				mc := iic.New(p.sys.Copy(), p.bad)
+				*mc.Options() = opts
+				start := time.Now()
+				res, err := mc.Try()
+				dur := time.Since(start)
+				if err != nil {
+					log.Printf("tune: %s with %s: %s", p.name, c, err)
+					res = 0
+				}
+				mu.Lock()
+				cut = cut || (capped && res == 0)
+				runs = append(runs, tuneRun{c: c, mc: mc, res: res, dur: dur})
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, c := range cfgs {
+		ch <- c
+	}
+	close(ch)
+	wg.Wait()
+	if cut {
+		return cfgs, false
+	}
+	status := make(map[int]int)
+	for _, r := range runs {
+		c := r.c
+		c.runs++
+		if r.res != 0 {
+			c.solved++
+			c.cost += r.dur
+		} else {
+			c.cost += 2 * *tuneOpts.Dur
+		}
+		status[r.res]++
+	}
+	if status[1] == 0 || status[-1] == 0 {
+		return cfgs, true
+	}
+	log.Printf("tune: %s: configurations disagree on reachability, verifying", p.name)
+	wrong := make(map[*tuneConfig]bool)
+	for _, r := range runs {
+		if r.res == 0 {
+			continue
+		}
+		if err := tuneVerify(r, p); err != nil {
+			log.Printf("tune: %s: discarding %s: %s", p.name, r.c, err)
+			wrong[r.c] = true
+		}
+	}
+	var res []*tuneConfig
+	for _, c := range cfgs {
+		if !wrong[c] {
+			res = append(res, c)
+		}
+	}
+	return res, true
+}
+
+// tuneVerify verifies the trace or invariant found by run `r` on `p`,
+// within -dur.
+func tuneVerify(r tuneRun, p *tuneProblem) error {
+	out := &reach.Output{}
+	if err := r.mc.FillOutput(out); err != nil {
+		return err
+	}
+	res := out.Results()[0]
+	switch {
+	case res.IsReachable():
+		if res.Trace == nil {
+			return fmt.Errorf("reachable without a trace")
+		}
+		if errs := res.Trace.Verify(p.sys); len(errs) != 0 {
+			return errs[0]
+		}
+	case res.IsUnreachable():
+		if fs := res.Invariant.Check(p.sys, p.bad, *tuneOpts.Dur); len(fs) != 0 {
+			return fs[0]
+		}
+	}
+	return nil
+}
+
+// tuneDiscard removes the configurations which cost both more than -factor
+// times the best configuration and more than tuneMinDelta above it.
+func tuneDiscard(cfgs []*tuneConfig) []*tuneConfig {
+	best := tuneBest(cfgs)
+	lim := time.Duration(*tuneOpts.Factor * float64(best.cost))
+	if lim < best.cost+tuneMinDelta {
+		lim = best.cost + tuneMinDelta
+	}
+	var res []*tuneConfig
+	for _, c := range cfgs {
+		if c.cost <= lim {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// tuneBest returns the configuration with the least cost, preferring
+// earlier configurations, and so the defaults, among equals.
+func tuneBest(cfgs []*tuneConfig) *tuneConfig {
+	best := cfgs[0]
+	for _, c := range cfgs[1:] {
+		if c.cost < best.cost {
+			best = c
+		}
+	}
+	return best
+}
+
+// readJSONFile reads the json file `p` into `v`.
+func readJSONFile(p string, v interface{}) error {
+	bs, err := ioutil.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bs, v)
+}
+
+// writeJSONFile writes `v` as indented json to the file `p`.
+func writeJSONFile(p string, v interface{}) error {
+	bs, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p, append(bs, '\n'), 0644)
+}
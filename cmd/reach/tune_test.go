@@ -0,0 +1,110 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/reach/iic"
+)
+
+func TestTuneDiscard(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		costs []time.Duration
+		kept  []int
+	}{
+		{"single", []time.Duration{time.Second}, []int{0}},
+		{"equal", []time.Duration{time.Second, time.Second}, []int{0, 1}},
+		{"factor", []time.Duration{time.Second, 1500 * time.Millisecond, 1600 * time.Millisecond}, []int{0, 1}},
+		{"best last", []time.Duration{4 * time.Second, time.Second, 3 * time.Second}, []int{1}},
+		// within tuneMinDelta of the best.
+		{"min delta", []time.Duration{0, 50 * time.Millisecond, 200 * time.Millisecond}, []int{0, 1}},
+	} {
+		cfgs := make([]*tuneConfig, len(tc.costs))
+		for i, c := range tc.costs {
+			cfgs[i] = &tuneConfig{cost: c}
+		}
+		res := tuneDiscard(cfgs)
+		ok := len(res) == len(tc.kept)
+		for i := 0; ok && i < len(res); i++ {
+			ok = res[i] == cfgs[tc.kept[i]]
+		}
+		if !ok {
+			t.Errorf("%s: kept %d configurations, expected %v", tc.name, len(res), tc.kept)
+		}
+	}
+}
+
+func TestTuneRoundBudget(t *testing.T) {
+	// a latch following an input, reachable in one step.
+	s := logic.NewS()
+	m := s.Latch(s.F)
+	s.SetNext(m, s.Lit())
+	p := &tuneProblem{name: "follow", sys: s, bad: m}
+	cfgs := tuneConfigs(rand.New(rand.NewSource(1)), 2)
+	if _, ok := tuneRound(cfgs, p, time.Now().Add(-time.Second)); ok {
+		t.Errorf("round past the deadline counted")
+	}
+	for _, c := range cfgs {
+		if c.runs != 0 || c.cost != 0 {
+			t.Errorf("%s: %d runs costing %s past the deadline", c, c.runs, c.cost)
+		}
+	}
+	kept, ok := tuneRound(cfgs, p, time.Now().Add(time.Minute))
+	if !ok {
+		t.Fatalf("round within the budget not counted")
+	}
+	if len(kept) != len(cfgs) {
+		t.Errorf("kept %d of %d agreeing configurations", len(kept), len(cfgs))
+	}
+	for _, c := range cfgs {
+		if c.runs != 1 || c.solved != 1 {
+			t.Errorf("%s: solved %d of %d runs, expected 1 of 1", c, c.solved, c.runs)
+		}
+	}
+}
+
+func TestTuneVerify(t *testing.T) {
+	// a latch following an input, and the same latch stuck at false.
+	follow, stuck := logic.NewS(), logic.NewS()
+	m := follow.Latch(follow.F)
+	follow.SetNext(m, follow.Lit())
+	n := stuck.Latch(stuck.F)
+	stuck.Lit()
+	stuck.SetNext(n, stuck.F)
+	p := &tuneProblem{name: "follow", sys: follow, bad: m}
+	mc := iic.New(follow.Copy(), m)
+	res, err := mc.Try()
+	if res != 1 || err != nil {
+		t.Fatalf("got %d %v, expected 1", res, err)
+	}
+	r := tuneRun{mc: mc, res: res}
+	if err := tuneVerify(r, p); err != nil {
+		t.Errorf("valid trace: %s", err)
+	}
+	// the trace does not hold if the latch is stuck.
+	if err := tuneVerify(r, &tuneProblem{name: "stuck", sys: stuck, bad: n}); err == nil {
+		t.Errorf("invalid trace verified")
+	}
+}
+
+func TestTuneOptionsDuration(t *testing.T) {
+	fn := testAiger(t)
+	out := filepath.Join(t.TempDir(), "opts.json")
+	if _, stderr, code := runReach(t, "tune", "-n", "2", "-dur", "3s", "-o", out, fn); code != 0 {
+		t.Fatalf("tune exited %d: %s", code, stderr)
+	}
+	opts := &iic.Options{}
+	if err := readJSONFile(out, opts); err != nil {
+		t.Fatal(err)
+	}
+	if def := iic.NewOptions().Duration; opts.Duration != def {
+		t.Errorf("written Duration %s not the default %s", opts.Duration, def)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/reach"
+)
+
+var vcdCmd = &subCmd{
+	Name:  "vcd",
+	Flags: flag.NewFlagSet("vcd", flag.ExitOnError),
+	Run:   doVcd,
+	Init:  initVcd,
+	Usage: "reach vcd [opts] <output>",
+	Short: `vcd outputs value change dump waveforms of traces in an output directory.`,
+	Long: `
+vcd outputs the traces in an output directory as value change dump (vcd) files,
+which can be viewed with waveform viewers such as GTKWave.  Inputs, latches and
+watches are dumped, named by the aiger symbol table where available.  With
+-gates, all AND gates are recomputed from the aiger and dumped as well.
+
+By default, the output is written to stdout.  Otherwise, a file
+bad-<lit>.vcd is written in the directory given by -o for each trace.
+`}
+
+var vcdOpts = struct {
+	outDir *string
+	gates  *bool
+}{}
+
+func initVcd(cmd *subCmd) {
+	flags := cmd.Flags
+	vcdOpts.outDir = flags.String("o", "", "output directory for vcd files.")
+	vcdOpts.gates = flags.Bool("gates", false, "dump all AND gates.")
+	flags.Usage = func() {
+		fmt.Println(cmd.Usage)
+		flags.PrintDefaults()
+		fmt.Println(cmd.Long)
+	}
+}
+
+func doVcd(cmd *subCmd, args []string) {
+	flags := cmd.Flags
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "no output directories specified.\n")
+		return
+	}
+	if flags.NArg() > 1 {
+		fmt.Fprintf(os.Stderr, "too many output directories specified.\n")
+		return
+	}
+	if err := doVcdArg(cmd, flags.Arg(0)); err != nil {
+		log.Printf("error writing vcd for '%s': %s\n", flags.Arg(0), err.Error())
+	}
+}
+
+func doVcdArg(cmd *subCmd, arg string) error {
+	flags := cmd.Flags
+	st, err := os.Stat(arg)
+	if os.IsNotExist(err) {
+		return err
+	}
+	if !st.IsDir() && !reach.IsArchive(arg) {
+		flags.SetOutput(os.Stderr)
+		flags.Usage()
+		fmt.Fprintf(os.Stderr, "cannot output vcd, need reach output dir with a trace.\n")
+		os.Exit(2)
+	}
+	return doVcdOutput(arg)
+}
+
+func doVcdOutput(arg string) error {
+	out, err := reach.OpenOutput(arg)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	aig, err := out.Aiger()
+	if err != nil {
+		return err
+	}
+	names := reach.AigerNames(aig)
+	var s *logic.S
+	if *vcdOpts.gates {
+		s = aig.Sys()
+	}
+	for i, b := range out.Results() {
+		if !b.IsReachable() {
+			continue
+		}
+		trace, err := out.Trace(i)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading trace: %s\n", err.Error())
+			continue
+		}
+		w, err := vcdWriter(b)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error opening vcd output: %s\n", err.Error())
+			continue
+		}
+		if err := trace.EncodeVCD(w, names, s); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing vcd: %s\n", err.Error())
+		}
+		if w != os.Stdout {
+			w.Close()
+			fmt.Printf("wrote vcd for %s to %s.\n", b, vcdOutPath(b))
+		}
+	}
+	return nil
+}
+
+func vcdWriter(bad *reach.Result) (io.WriteCloser, error) {
+	if *vcdOpts.outDir == "" || *vcdOpts.outDir == "-" {
+		return os.Stdout, nil
+	}
+	return os.Create(vcdOutPath(bad))
+}
+
+func vcdOutPath(b *reach.Result) string {
+	return filepath.Join(*vcdOpts.outDir, fmt.Sprintf("bad-%d.vcd", b.M))
+}
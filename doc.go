@@ -15,7 +15,7 @@
 // aid in coordinating checkers.
 //
 //   1. `Output`, which is the output of a checker from analyzing a logic.S and
-//   a, or some, bad states.
+//   a, or some, bad states, stored in a directory or a single file archive.
 //
 //   2. `Result`, which is the result of analysing a single reachability query
 //   with one set of bad states represented by a z.Lit in a logic.S.
@@ -24,6 +24,15 @@
 //
 //   4. `Trace`, which is a trace of a sequential logic system.
 //
+//   5. `Invariant`, which is an inductive invariant in cnf showing a bad
+//   state unreachable, and which can be checked, simplified and read or
+//   written as dimacs or aiger.
+//
 // These are concepts related to coordination of checkers. Various checkers are
 // found in the subpackages of reach.
+//
+// The reach packages do not exit the process on failure.  Errors wrap one of
+// the Err variables of this package, such as ErrTraceIncoherent or ErrTimeout,
+// and can be tested with errors.Is.  Diagnostics, including verbose output of
+// checkers, go to the Logger given to SetLogger.
 package reach
@@ -0,0 +1,52 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import "errors"
+
+// Errors returned by the reach packages.  Errors with more detail wrap one of
+// these, and so can be tested with errors.Is.
+var (
+	// ErrTimeout indicates a time limit was reached before a check
+	// completed.
+	ErrTimeout = errors.New("ErrTimeout")
+
+	// ErrTraceIncoherent indicates a trace does not correspond to a
+	// simulation of its circuit.
+	ErrTraceIncoherent = errors.New("ErrTraceIncoherent")
+
+	// ErrInvariantInit indicates an invariant does not hold in some
+	// initial state.
+	ErrInvariantInit = errors.New("ErrInvariantInit")
+
+	// ErrInvariantNotInductive indicates an invariant does not hold in some
+	// successor of a state in the invariant.
+	ErrInvariantNotInductive = errors.New("ErrInvariantNotInductive")
+
+	// ErrInvariantUnsafe indicates an invariant does not exclude the bad
+	// state.
+	ErrInvariantUnsafe = errors.New("ErrInvariantUnsafe")
+
+	// ErrInvariantFormat indicates an invariant is malformed.
+	ErrInvariantFormat = errors.New("ErrInvariantFormat")
+
+	// ErrCertificate indicates an aiger certificate does not correspond to
+	// its model.
+	ErrCertificate = errors.New("ErrCertificate")
+
+	// ErrInternal indicates an internal error in a checker.
+	ErrInternal = errors.New("ErrInternal")
+
+	// ErrContradiction indicates that results for the same bad state
+	// disagree on whether it is reachable.
+	ErrContradiction = errors.New("ErrContradiction")
+
+	// ErrOutputMismatch indicates an existing output directory is for a
+	// different aiger.
+	ErrOutputMismatch = errors.New("ErrOutputMismatch")
+
+	// ErrOutputCorrupt indicates files of an output directory are missing
+	// or do not match its manifest.
+	ErrOutputCorrupt = errors.New("ErrOutputCorrupt")
+)
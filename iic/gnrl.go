@@ -6,7 +6,6 @@ package iic
 import (
 	"fmt"
 	"io"
-	"log"
 	"math/rand"
 
 	"github.com/go-air/gini/logic"
@@ -53,7 +52,7 @@ func newGnrl(sat *satmon, trans *logic.S, d *lits.T, obs *obs.Set, initVals []in
 func (g *gnrl) gnrlize(o obs.Id, primer *reach.Primer) bool {
 	defer func() {
 		if res := g.sat.sat.Untest(); res == -1 {
-			panic("untest handle ob unsat ind.")
+			panic(internalError("untest handle ob unsat ind."))
 		}
 	}()
 	g.nC++
@@ -103,7 +102,7 @@ start:
 			orgLen++
 		} else {
 			// counterexample found but at wrong level
-			log.Fatalf("ErrInternalGnrlNoInit %s %v", g.obs.String(o), g.ns)
+			panic(internalErrorf("ErrInternalGnrlNoInit %s %v", g.obs.String(o), g.ns))
 		}
 	}
 	g.cleanupStep()
@@ -203,7 +202,7 @@ func (g *gnrl) placeInit(ms []z.Lit) {
 	}
 	m := ms[0]
 	if g.initVals[m.Var()]+m.Sign() != 0 {
-		panic("no init in placeInit")
+		panic(internalError("no init in placeInit"))
 	}
 }
 
@@ -223,8 +222,7 @@ func (g *gnrl) cnfMs() (ms []z.Lit, init z.Lit) {
 		g.ns = append(g.ns, init)
 		return g.ns, init
 	}
-	log.Fatalf("gnrl: init not ok: g.ns %v\n", g.ns)
-	return g.ns, z.LitNull
+	panic(internalErrorf("gnrl: init not ok: g.ns %v", g.ns))
 }
 
 func (g *gnrl) clsInitOk(ms []z.Lit) z.Lit {
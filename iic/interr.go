@@ -0,0 +1,20 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package iic
+
+import "fmt"
+
+// internalError is the panic value for inconsistencies detected by iic.  Try
+// recovers from such panics and returns an error wrapping
+// reach.ErrInternal.  Other panics, such as runtime errors, are not
+// recovered.
+type internalError string
+
+func (e internalError) Error() string {
+	return string(e)
+}
+
+func internalErrorf(format string, args ...interface{}) internalError {
+	return internalError(fmt.Sprintf(format, args...))
+}
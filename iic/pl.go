@@ -118,7 +118,7 @@ func (l *pl) conSift(f *cnf.T, sat *satmon, obs *obs.Set, init, bad z.Lit) (time
 	sat.Assume(bad.Not())
 
 	if st, _ := sat.sat.Test(nil); st != 0 {
-		panic("sift test solved")
+		panic(internalError("sift test solved"))
 	}
 	toAdd := make([]z.Lit, 0, 32)
 	defer func() {
@@ -22,7 +22,6 @@ package iic
 import (
 	"fmt"
 	"io"
-	"log"
 	"math/rand"
 	"sort"
 	"time"
@@ -30,6 +29,7 @@ import (
 	"github.com/go-air/gini"
 	"github.com/go-air/gini/logic"
 	"github.com/go-air/gini/z"
+	"github.com/go-air/reach"
 	"github.com/go-air/reach/iic/internal/lits"
 	"github.com/go-air/reach/iic/internal/queue"
 )
@@ -92,7 +92,7 @@ func (p *pp) processTo(sat *gini.Gini, deadLine *time.Time) {
 		}
 	}
 	if p.verbose {
-		fmt.Printf("done preprocessing.\n")
+		reach.Logf("done preprocessing.\n")
 	}
 	for i := range p.clauses {
 		c := &p.clauses[i]
@@ -112,7 +112,7 @@ func (p *pp) ssr() bool {
 	var res = false
 	var ms []z.Lit
 	if p.verbose {
-		fmt.Printf("pp ssr |q|=%d\n", p.todo.Len())
+		reach.Logf("pp ssr |q|=%d\n", p.todo.Len())
 	}
 	for p.todo.Len() > 0 {
 		i := p.todo.Pop()
@@ -137,7 +137,7 @@ func (p *pp) ssr() bool {
 func (p *pp) addSsrs(c *clause, id int, rez *lits.Resolver, m z.Lit) {
 	occs := p.occs[m.Not()]
 	if !rez.SetPivot(m.Var()) {
-		log.Fatalf("rez %s set piv %s c%d %v\n", rez, m, id, p.lits.Get(c.ms))
+		panic(internalErrorf("rez %s set piv %s c%d %v", rez, m, id, p.lits.Get(c.ms)))
 	}
 	var ms = p.lits.Get(c.ms)
 	var N = len(ms)
@@ -163,10 +163,10 @@ func (p *pp) addSsrs(c *clause, id int, rez *lits.Resolver, m z.Lit) {
 		orgLen := len(p.ssrAdd)
 		p.ssrAdd, ok = rez.Resolve(p.ssrAdd, oms)
 		if !ok {
-			panic("no resolve but contained by except")
+			panic(internalError("no resolve but contained by except"))
 		}
 		if len(p.ssrAdd)-orgLen != len(oms)-1 {
-			panic("wrong len resolve")
+			panic(internalError("wrong len resolve"))
 		}
 		p.ssrAdd = append(p.ssrAdd, 0)
 		rmq = append(rmq, oid)
@@ -219,7 +219,7 @@ func (p *pp) rmSubsumed(c *clause, id int) bool {
 		p.remove(id)
 	}
 	if p.verbose && len(rmq) != 0 {
-		fmt.Printf("rmd %d clauses\n", len(rmq))
+		reach.Logf("rmd %d clauses\n", len(rmq))
 	}
 	return len(rmq) > 0
 }
@@ -233,7 +233,7 @@ func (p *pp) tryElim(m z.Lit) (dc, dm int) {
 	for _, id := range p.occs[m] {
 		c := &p.clauses[id]
 		if !rez.Set(p.lits.Get(c.ms), pivot) {
-			panic("wilma!")
+			panic(internalError("wilma!"))
 		}
 		for _, oid := range p.occs[m.Not()] {
 			oc := &p.clauses[oid]
@@ -260,7 +260,7 @@ func (p *pp) tryElim(m z.Lit) (dc, dm int) {
 
 func (p *pp) elim(m z.Lit) {
 	if p.verbose {
-		fmt.Printf("eiminating %s\n", m)
+		reach.Logf("eiminating %s\n", m)
 	}
 	pivot := m.Var()
 	rez := &lits.Resolver{}
@@ -271,7 +271,7 @@ func (p *pp) elim(m z.Lit) {
 	for _, id := range occs {
 		c := &p.clauses[id]
 		if !rez.Set(p.lits.Get(c.ms), pivot) {
-			panic("wilma!")
+			panic(internalError("wilma!"))
 		}
 		for _, oid := range noccs {
 			oc := &p.clauses[oid]
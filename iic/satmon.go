@@ -6,6 +6,7 @@ package iic
 import (
 	"fmt"
 	"io"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-air/gini"
@@ -15,7 +16,8 @@ import (
 // TBD: add relative calls and mean/stddev online duration.
 
 // satmon is a sat wrapper that monitors time spent in sat calls and total
-// number of sat calls.
+// number of sat calls.  Calls return 0 without solving once the deadline
+// is reached or, if stop is not nil, once *stop is set.
 type satmon struct {
 	name     string
 	calls    int64
@@ -24,13 +26,17 @@ type satmon struct {
 	sat      *gini.Gini
 	dur      time.Duration
 	deadline *time.Time
+	stop     *int32
 }
 
-func newSatMon(name string, sat *gini.Gini, deadline *time.Time) *satmon {
-	return &satmon{name: name, sat: sat, deadline: deadline}
+func newSatMon(name string, sat *gini.Gini, deadline *time.Time, stop *int32) *satmon {
+	return &satmon{name: name, sat: sat, deadline: deadline, stop: stop}
 }
 
 func (m *satmon) Try() int {
+	if m.stop != nil && atomic.LoadInt32(m.stop) != 0 {
+		return 0
+	}
 	start := time.Now()
 	dur := time.Until(*m.deadline)
 	res := m.sat.Try(dur)
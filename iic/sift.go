@@ -62,14 +62,14 @@ func (s *sifter) sift(dst []z.Lit, c cnf.Id) (toAdd []z.Lit, timeOk bool) {
 		case 0:
 			return dst, false
 		case 1:
-			fmt.Printf("orgLen %d now %v\n", orgLen, s.ms)
-			for _, m := range s.ms {
-				fmt.Printf("prime: %s\n", s.pri.Prime(m))
+			if debugSift {
+				fmt.Printf("orgLen %d now %v\n", orgLen, s.ms)
+				for _, m := range s.ms {
+					fmt.Printf("prime: %s\n", s.pri.Prime(m))
+				}
+				s.sat.sat.Write(os.Stdout)
 			}
-			s.sat.sat.Write(os.Stdout)
-			os.Stdout.Sync()
-			panic("wilma!")
-			return dst, false
+			panic(internalErrorf("sift: %v not consecutive", s.ms))
 		case -1:
 			s.extractNs(yMap)
 
@@ -134,5 +134,5 @@ func (s *sifter) ensureInit() {
 		}
 	}
 	fmt.Printf("ns %v\n", s.ns)
-	panic("no init")
+	panic(internalError("no init"))
 }
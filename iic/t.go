@@ -5,8 +5,8 @@ package iic
 
 import (
 	"fmt"
-	"log"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-air/reach/iic/internal/lits"
@@ -25,6 +25,7 @@ type T struct {
 	orgTransLen int
 	init        z.Lit
 	bad         z.Lit // bad state over latches
+	inBad       z.Lit // bad state given to New if it depends on inputs, see delayBad
 	badPrime    z.Lit // bad state over next states of latches
 	rResult     *reach.Result
 	sat         *gini.Gini
@@ -51,11 +52,22 @@ type T struct {
 	mps       []z.Lit // scratch primes to justify
 	initVals  []int8
 	learnts   int64
+	nObs      int64 // proof obligations created
+	stop      int32 // set by Stop
+	err       error // internal error of a previous call to Try
 }
 
 // New creates a new incremental inductive model checker from a transition
 // system and bad state literal.
+//
+// If the bad state depends on inputs, New checks a copy of `trans` with the
+// bad state delayed by a latch, as described in delayBad.
 func New(trans *logic.S, bad z.Lit) *T {
+	inBad := z.LitNull
+	if dependsOnInputs(trans, bad) {
+		inBad = bad
+		trans, bad = delayBad(trans, bad)
+	}
 	init := trans.T
 	initVals := make([]int8, trans.Len())
 	if debugState {
@@ -82,7 +94,7 @@ func New(trans *logic.S, bad z.Lit) *T {
 		}
 		trans.SetInit(m, z.LitNull)
 	}
-	res := &T{trans: trans, init: init, bad: bad, orgTransLen: trans.Len(),
+	res := &T{trans: trans, init: init, bad: bad, inBad: inBad, orgTransLen: trans.Len(),
 		sat: gini.NewVc(trans.Len()+16384, trans.Len()+16384)}
 	res.lits = lits.New()
 	res.initVals = initVals
@@ -93,14 +105,14 @@ func New(trans *logic.S, bad z.Lit) *T {
 	res.badPrime = res.prime(bad)
 	res.obs = obs.NewSet(res.lits)
 	res.opts = NewOptions()
-	res.blkSat = newSatMon("block", res.sat, &res.deadLine)
-	res.propSat = newSatMon("prop", res.sat, &res.deadLine)
-	res.gnrlSat = newSatMon("gnrl", res.sat, &res.deadLine)
+	res.blkSat = newSatMon("block", res.sat, &res.deadLine, &res.stop)
+	res.propSat = newSatMon("prop", res.sat, &res.deadLine, &res.stop)
+	res.gnrlSat = newSatMon("gnrl", res.sat, &res.deadLine, &res.stop)
 	res.gnrl = newGnrl(res.gnrlSat, trans, res.lits, res.obs, res.initVals)
 	res.justifier = newJustifier(trans)
 	res.pushes = newNp(res.cnf, res.propSat, res.primer, res.obs, res.initVals, res.init, res.bad)
 	res.preproc = newPp(res.trans, res.bad)
-	res.rResult = &reach.Result{M: res.bad}
+	res.rResult = &reach.Result{M: res.bad, Engine: "iic"}
 	res.maxDepth = 1 << 30
 	res.cnf.SetRemoveHook(func(f *cnf.T, c, by cnf.Id, k int) {
 		res.pushes.crmHook(f, c, by, k)
@@ -109,10 +121,85 @@ func New(trans *logic.S, bad z.Lit) *T {
 	return res
 }
 
+// dependsOnInputs returns whether `m` depends on an input of `trans` other
+// than through latches.
+func dependsOnInputs(trans *logic.S, m z.Lit) bool {
+	seen := make(map[z.Var]bool)
+	stk := []z.Lit{m}
+	for len(stk) != 0 {
+		m = stk[len(stk)-1]
+		stk = stk[:len(stk)-1]
+		if seen[m.Var()] {
+			continue
+		}
+		seen[m.Var()] = true
+		switch trans.Type(m) {
+		case logic.SInput:
+			return true
+		case logic.SAnd:
+			a, b := trans.Ins(m)
+			stk = append(stk, a, b)
+		}
+	}
+	return false
+}
+
+// delayBad returns a copy of `trans` with a new latch, initially false,
+// whose next state is `bad`, together with the latch.
+//
+// iic blocks bad states as sets of states, which is wrong for a bad state
+// depending on inputs, since a state blocked for some inputs may reach the
+// bad state with others.  The latch is a bad state over latches which is
+// reachable in one more step than `bad`.  Results for the latch are turned
+// into results for `bad` by FillOutput and Invariant, see undelay.
+func delayBad(trans *logic.S, bad z.Lit) (*logic.S, z.Lit) {
+	trans = trans.Copy()
+	m := trans.Latch(trans.F)
+	trans.SetNext(m, bad)
+	return trans, m
+}
+
+// undelay turns the clauses `inv` over the transition system with the
+// delayed bad state into clauses over the original one, by assuming the
+// latch of the delayed bad state false.
+//
+// If `inv` is inductive with the latch false, then so are the resulting
+// clauses, which moreover exclude the bad state for all inputs, since the
+// latch remains false.
+func (t *T) undelay(inv reach.Invariant) reach.Invariant {
+	var res reach.Invariant
+	inv.Forall(func(_ int, c []z.Lit) {
+		for _, m := range c {
+			if m == t.bad.Not() {
+				return
+			}
+		}
+		for _, m := range c {
+			if m != t.bad {
+				res.Add(m)
+			}
+		}
+		res.Add(0)
+	})
+	return res
+}
+
 func (t *T) Options() *Options {
 	return t.opts
 }
 
+// Stop asks Try, running in another goroutine, to return 0 as soon as
+// possible, as if its time limit were reached, so that FillOutput gives the
+// depth reached so far.  A SAT call in progress is not interrupted.  Once
+// Stop is called, subsequent calls to Try return 0.
+func (t *T) Stop() {
+	atomic.StoreInt32(&t.stop, 1)
+}
+
+func (t *T) stopped() bool {
+	return atomic.LoadInt32(&t.stop) != 0
+}
+
 func (t *T) installOpts() {
 	t.preproc.verbose = t.opts.Verbose
 	if t.opts.DeepObs {
@@ -128,14 +215,39 @@ func (t *T) installOpts() {
 	t.pushes.conSiftPull = t.opts.ConsecuSiftPull
 }
 
+// verifyDur is the time limit for verifying an invariant, which is not
+// limited by Options.Duration.
+const verifyDur = time.Duration(1<<63 - 1)
+
 // Try tries to solve the reachability problem specified in New.
 //
 // Try returns
 //
 //  1 if there is a trace to the bad state
-//  0 if timed out
+//  0 if timed out or stopped
 //  -1 if there cannot be a trace to the bad state
-func (t *T) Try() int {
+//
+// If Options().VerifyInvariant is set, then before returning -1 Try verifies
+// the inductive invariant it found with reach.Invariant.Check.  If
+// verification fails, Try returns 0 and a non-nil error of type
+// *reach.InvariantFailure.  If iic detects an internal inconsistency, Try
+// returns 0 and an error wrapping reach.ErrInternal, and as the state of t
+// is then inconsistent, subsequent calls return the same error.  Otherwise,
+// the returned error is nil.
+func (t *T) Try() (res int, err error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			ie, ok := r.(internalError)
+			if !ok {
+				panic(r)
+			}
+			t.err = fmt.Errorf("%w: %s", reach.ErrInternal, ie)
+			res, err = 0, t.err
+		}
+	}()
 	t.installOpts()
 	if t.opts.Preprocess {
 		t.preproc.processTo(t.sat, &t.deadLine)
@@ -143,7 +255,7 @@ func (t *T) Try() int {
 		t.trans.ToCnf(t.sat)
 	}
 	if res := t.ckInit(); res != -1 {
-		return res
+		return res, nil
 	}
 	t.rResult.Depth = 1
 	t.cnf.PushK()
@@ -164,28 +276,33 @@ func (t *T) Try() int {
 			}
 		default:
 		}
+		if t.stopped() {
+			return 0, nil
+		}
 		ob := t.obs.Choose()
 		if ob == 0 {
 			K := t.obs.MaxK() + 1
 			if K > t.maxDepth {
-				return 0
+				return 0, nil
 			}
 			t.cnf.PushK()
 			fixedPoint, timeOk := t.pushes.prop(K)
 			if !timeOk {
-				return 0
+				return 0, nil
 			}
 			if fixedPoint {
-				t.rResult.SetUnreachable()
-				if err := t.verifyInd(); err != nil {
-					log.Fatalf("error verifying invariable: %s\n", err)
+				if t.opts.VerifyInvariant {
+					if err := t.verifyInd(); err != nil {
+						return 0, err
+					}
 				}
+				t.rResult.SetUnreachable()
 				t.pushes.push()
-				return -1
+				return -1, nil
 			}
 			t.rResult.Depth = K
 			if t.opts.Verbose {
-				fmt.Printf("increase K to %d\n", K)
+				reach.Logf("increase K to %d\n", K)
 				t.stats()
 			}
 
@@ -211,15 +328,15 @@ func (t *T) Try() int {
 				t.traceHd = nob
 				t.rResult.Depth = t.obs.DistToBad(nob)
 				t.rResult.SetReachable(nil)
-				return 1
+				return 1, nil
 			}
 		case obTimeout:
 			if debugObq {
 				fmt.Printf("[obq]: timeout.\n")
 			}
-			return 0
+			return 0, nil
 		default:
-			panic(fmt.Sprintf("unknown obres: %s", res))
+			panic(internalErrorf("unknown obres: %s", res))
 		}
 	}
 }
@@ -255,7 +372,7 @@ func (t *T) handleObBlockOrExtend(o obs.Id) (obRes, obs.Id) {
 	st := t.blockCallSat()
 	if st == 0 {
 		if st := t.sat.Untest(); st == -1 {
-			panic("untest handle ob sat")
+			panic(internalError("untest handle ob sat"))
 		}
 		return obTimeout, 0
 	}
@@ -274,7 +391,7 @@ func (t *T) handleObBlockOrExtend(o obs.Id) (obRes, obs.Id) {
 	}
 	res, nob := t.extend(o, t.obs.Ms(o))
 	if res := t.sat.Untest(); res == -1 {
-		panic("untest handle ob unsat")
+		panic(internalError("untest handle ob unsat"))
 	}
 	return res, nob
 }
@@ -302,6 +419,7 @@ func (t *T) extend(o obs.Id, mps []z.Lit) (obRes, obs.Id) {
 		//log.Fatalf("zero j of %v\n", t.obs.ms(o))
 	}
 	nob := t.obs.Extend(o, ms, ini)
+	t.nObs++
 	if debugState {
 		fmt.Printf("\tnob %s <- %s\n", t.obs.String(nob), t.obs.String(o))
 	}
@@ -354,7 +472,7 @@ func (t *T) handleObIndGnr(o obs.Id) {
 		for _, m := range ms {
 			t.sat.Assume(t.primer.Prime(m).Not())
 		}
-		res := t.sat.Try(time.Until(t.deadLine))
+		res := t.callSat()
 		switch res {
 		case 0:
 			k = K
@@ -408,15 +526,10 @@ func (t *T) ckInit() int {
 	case 0:
 		return 0
 	case 1:
+		// the trace is the initial state, with the root obligation,
+		// which is the bad state.
 		t.rResult.SetReachable(nil)
-		ms := make([]z.Lit, 0, len(t.trans.Latches))
-		for _, m := range t.trans.Latches {
-			if !t.sat.Value(m) {
-				m = m.Not()
-			}
-			ms = append(ms, m)
-		}
-		t.traceHd = t.obs.Extend(t.obs.Root(), ms, 0)
+		t.traceHd = t.obs.Root()
 		return 1
 	}
 	t.sat.Assume(t.init)
@@ -427,92 +540,132 @@ func (t *T) ckInit() int {
 		return res
 	}
 	t.rResult.SetReachable(nil)
+	t.rResult.Depth = 1
 	ms := make([]z.Lit, 0, len(t.trans.Latches))
-	ns := make([]z.Lit, 0, len(t.trans.Latches))
 	for _, m := range t.trans.Latches {
-		mp := t.prime(m)
-		if !t.sat.Value(mp) {
-			mp = mp.Not()
-		}
-		ns = append(ns, mp)
 		if !t.sat.Value(m) {
 			m = m.Not()
 		}
 		ms = append(ms, m)
 	}
-	p := t.obs.Extend(t.obs.Root(), ns, z.LitNull)
-	t.traceHd = t.obs.Extend(p, ms, z.LitNull)
+	t.traceHd = t.obs.Extend(t.obs.Root(), ms, z.LitNull)
 	return 1
 }
 
+// verifyInd verifies that the clauses at level K together with not(bad)
+// form an inductive invariant, which is the invariant given by FillOutput.
+// The invariant is checked with reach.Invariant.Check on a copy of the
+// transition system with its initial values, independently of the solver
+// and the primer of t.  verifyInd returns the first failure, if any.
 func (t *T) verifyInd() error {
-	K := t.cnf.K()
+	s := t.trans.Copy()
+	for _, m := range s.Latches {
+		switch t.initVals[m.Var()] {
+		case 1:
+			s.SetInit(m, s.T)
+		case -1:
+			s.SetInit(m, s.F)
+		}
+	}
+	inv := t.levelInvariant(t.cnf.K())
 	if debugVerifyInd {
-		fmt.Printf("verifying inductive result at depth %d.\n", K)
+		fmt.Printf("verifying inductive result at depth %d: %s.\n", t.cnf.K(), inv.Size())
 	}
-	t.sat.Assume(t.bad.Not())
-	t.cnf.AssumeLevel(K)
-	defer t.sat.Untest()
-
-	if res, _ := t.sat.Test(nil); res == -1 {
-		return fmt.Errorf("ErrIndCurLevelUnsat")
-	}
-	t.sat.Assume(t.badPrime)
-	switch st := t.sat.Solve(); st {
-	case 1:
-		return fmt.Errorf("not(bad) is not inductive")
-	case -1:
-		if debugVerifyInd {
-			fmt.Printf("verified that not(bad) is inductive.\n")
-		}
+	fs := inv.Check(s, t.bad, verifyDur)
+	if len(fs) == 0 {
+		return nil
 	}
+	return fs[0]
+}
 
-	var err error
-	t.cnf.Forall(K, func(f *cnf.T, c cnf.Id) {
-		if err != nil {
-			return
-		}
-		if debugVerifyInd {
-			fmt.Printf("checking consecution for %s\n", c)
-		}
-		ms := f.Lits(c)
-		for _, m := range ms {
-			mp := t.prime(m)
-			t.sat.Assume(mp.Not())
-		}
-		switch st := t.sat.Solve(); st {
-		case 1:
-			err = fmt.Errorf("clause %v not consecutive", ms)
-		case 0:
-			err = fmt.Errorf("timeout during sanity check verifying invariant")
+// Invariant returns the inductive invariant found by the last call to Try,
+// or nil if Try did not show the bad state unreachable.  The invariant
+// consists of the clauses at the last level together with the negation of
+// the bad state, or, if the bad state depends on inputs, of these clauses
+// with the delayed bad state false.
+func (t *T) Invariant() reach.Invariant {
+	if !t.rResult.IsUnreachable() {
+		return nil
+	}
+	t.cnf.Simplify(t.cnf.K())
+	inv := t.levelInvariant(t.cnf.K())
+	if t.inBad != z.LitNull {
+		inv = t.undelay(inv)
+	}
+	return inv
+}
+
+// levelInvariant returns the clauses at level k together with not(bad).
+func (t *T) levelInvariant(k int) reach.Invariant {
+	var inv reach.Invariant
+	t.cnf.Forall(k, func(f *cnf.T, c cnf.Id) {
+		for _, m := range t.cnf.Lits(c) {
+			inv.Add(m)
 		}
+		inv.Add(0)
 	})
-	// TBD: initiation.
-	return nil
+	inv.Add(t.bad.Not())
+	inv.Add(0)
+	return inv
+}
+
+// frame returns the clauses of frame k, which are those at levels k through
+// K.  They hold in all states reachable within k steps.
+func (t *T) frame(k int) reach.Invariant {
+	var fr reach.Invariant
+	for i := k; i <= t.cnf.K(); i++ {
+		t.cnf.Forall(i, func(f *cnf.T, c cnf.Id) {
+			for _, m := range t.cnf.Lits(c) {
+				fr.Add(m)
+			}
+			fr.Add(0)
+		})
+	}
+	return fr
 }
 
 // FillOutput fills `o` with information about the last
 // call to Try.
-func (t *T) FillOutput(o *reach.Output) {
+//
+// If Try was stopped by Stop, the clauses of the frame at the depth reached
+// are added to the unknown result as its Frames.
+//
+// If a trace to the bad state cannot be generated, the result is added
+// without a trace and the error is returned.
+func (t *T) FillOutput(o *reach.Output) error {
+	var err error
 	t.rResult.Dur = time.Since(t.startTime)
+	t.rResult.Stats = map[string]int64{
+		"SatCalls":    t.blkSat.calls + t.propSat.calls + t.gnrlSat.calls,
+		"Obligations": t.nObs,
+		"Lemmas":      t.learnts}
 	if t.rResult.IsUnreachable() {
-		t.cnf.Simplify(t.cnf.K())
-		t.cnf.Forall(t.cnf.K(), func(f *cnf.T, c cnf.Id) {
-			for _, m := range t.cnf.Lits(c) {
-				t.rResult.Add(m)
-			}
-			t.rResult.Add(0)
-		})
-		t.rResult.Add(t.bad.Not())
-		t.rResult.Add(0)
+		t.rResult.Invariant = t.Invariant()
+	} else if !t.rResult.IsSolved() && t.stopped() {
+		t.rResult.Frames = t.frame(t.rResult.Depth)
+		if t.inBad != z.LitNull {
+			t.rResult.Frames = t.undelay(t.rResult.Frames)
+		}
 	} else if t.rResult.IsReachable() {
 		tr, terr := t.buildTrace()
 		if terr != nil {
-			log.Printf("error generating trace: %s", terr)
+			err = fmt.Errorf("generating trace: %w", terr)
+		} else {
+			t.rResult.Trace = tr
+		}
+	}
+	res := t.rResult
+	if t.inBad != z.LitNull {
+		// the delayed bad state is reached one step after the bad state.
+		r := *t.rResult
+		r.M = t.inBad
+		if r.Depth > 0 {
+			r.Depth--
 		}
-		t.rResult.Trace = tr
+		res = &r
 	}
-	o.AppendResult(t.rResult)
+	o.AppendResult(res)
+	return err
 }
 
 func (t *T) buildTrace() (*reach.Trace, error) {
@@ -522,10 +675,11 @@ func (t *T) buildTrace() (*reach.Trace, error) {
 		primer:   t.primer,
 		init:     t.init,
 		bad:      t.bad,
+		inBad:    t.inBad,
 		badPrime: t.badPrime,
 		hd:       t.traceHd,
 		obs:      t.obs,
-		sat:      newSatMon("tracegen", t.sat, &t.deadLine)}
+		sat:      newSatMon("tracegen", t.sat, &t.deadLine, nil)}
 	return tg.build()
 }
 
@@ -577,7 +731,7 @@ func (t *T) blockCallSat() int {
 
 func (t *T) callSat() int {
 	dur := time.Until(t.deadLine)
-	if dur < 0 {
+	if dur < 0 || t.stopped() {
 		return 0
 	}
 	return t.sat.Try(dur)
@@ -4,6 +4,9 @@
 package iic
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
@@ -30,7 +33,7 @@ func TestCounterSat(t *testing.T) {
 		bad = trans.And(bad, m)
 	}
 	dead := time.Now().Add(time.Hour)
-	sat := newSatMon("test", gini.New(), &dead)
+	sat := newSatMon("test", gini.New(), &dead, nil)
 
 	pri := reach.NewPrimer(trans, init, bad)
 	trans.ToCnf(sat.sat)
@@ -50,7 +53,7 @@ func TestIicTrivInd(t *testing.T) {
 	m := trans.Latch(trans.F)
 	trans.SetNext(m, m)
 	mc := New(trans, m)
-	if mc.Try() != -1 {
+	if res, err := mc.Try(); res != -1 || err != nil {
 		t.Errorf("got trace in triv unsat.")
 	}
 }
@@ -68,7 +71,11 @@ func TestIicCounter(t *testing.T) {
 		carry = trans.And(carry, m)
 	}
 	mc := New(trans, carry)
-	switch mc.Try() {
+	res, err := mc.Try()
+	if err != nil {
+		t.Error(err)
+	}
+	switch res {
 	case 1:
 	case 0:
 		t.Logf("iic timed out")
@@ -91,7 +98,11 @@ func TestIicNotCounter(t *testing.T) {
 	}
 	trans.SetNext(ms[N-1], trans.And(trans.Next(ms[N-1]), ms[0].Not()))
 	mc := New(trans, carry)
-	switch mc.Try() {
+	res, err := mc.Try()
+	if err != nil {
+		t.Error(err)
+	}
+	switch res {
 	case -1:
 	case 0:
 		t.Logf("timed out...")
@@ -100,6 +111,27 @@ func TestIicNotCounter(t *testing.T) {
 	}
 }
 
+func TestIicStop(t *testing.T) {
+	N := 10
+	trans := logic.NewS()
+	in := trans.Lit()
+	carry := trans.T
+	for i := 0; i < N; i++ {
+		m := trans.Latch(trans.F)
+		trans.SetNext(m, trans.Choice(trans.And(carry, in), m.Not(), m))
+		carry = trans.And(carry, m)
+	}
+	mc := New(trans, carry)
+	mc.Stop()
+	res, err := mc.Try()
+	if err != nil {
+		t.Error(err)
+	}
+	if res != 0 {
+		t.Errorf("got %d after stop, expected 0", res)
+	}
+}
+
 func TestIicFifo(t *testing.T) {
 	N := 4
 	trans := logic.NewS()
@@ -115,7 +147,11 @@ func TestIicFifo(t *testing.T) {
 		all = trans.And(all, m)
 	}
 	mc := New(trans, all)
-	switch mc.Try() {
+	res, err := mc.Try()
+	if err != nil {
+		t.Error(err)
+	}
+	switch res {
 	case 1:
 	case 0:
 		t.Logf("timed out.\n")
@@ -123,3 +159,180 @@ func TestIicFifo(t *testing.T) {
 		t.Errorf("got ind, expected cex")
 	}
 }
+
+func TestIicVerifyInd(t *testing.T) {
+	trans := logic.NewS()
+	m := trans.Latch(trans.F)
+	n := trans.Latch(trans.F)
+	trans.SetNext(m, m)
+	trans.SetNext(n, trans.Lit())
+	mc := New(trans, m)
+	if res, err := mc.Try(); res != -1 || err != nil {
+		t.Fatalf("got %d, %v in triv unsat.", res, err)
+	}
+	if err := mc.verifyInd(); err != nil {
+		t.Errorf("verifying valid invariant: %s", err)
+	}
+	for _, tc := range []struct {
+		ms  []z.Lit
+		err error
+	}{
+		{[]z.Lit{n.Not()}, reach.ErrInvariantNotInductive},
+		{[]z.Lit{m}, reach.ErrInvariantInit}} {
+		mc.cnf.Add(tc.ms, mc.cnf.K())
+		err := mc.verifyInd()
+		f, ok := err.(*reach.InvariantFailure)
+		if !ok || !errors.Is(err, tc.err) {
+			t.Errorf("clause %v: expected %s, got %v", tc.ms, tc.err, err)
+			continue
+		}
+		if len(f.Lits) != len(tc.ms) || f.Lits[0] != tc.ms[0] {
+			t.Errorf("clause %v: failure for clause %v", tc.ms, f.Lits)
+		}
+	}
+}
+
+// TestIicInputBad checks iic finds a trace to a bad state over inputs which
+// is reachable at depth 1, and an invariant for one which is unreachable.
+func TestIicInputBad(t *testing.T) {
+	trans := logic.NewS()
+	in := trans.Lit()
+	l := trans.Latch(trans.F)
+	trans.SetNext(l, in)
+	bad := trans.And(l, in.Not())
+	mc := New(trans, bad)
+	res, err := mc.Try()
+	if res != 1 || err != nil {
+		t.Fatalf("got %d %v, expected 1", res, err)
+	}
+	out := &reach.Output{}
+	if err := mc.FillOutput(out); err != nil {
+		t.Fatal(err)
+	}
+	r := out.Results()[0]
+	if r.M != bad || r.Depth != 1 {
+		t.Errorf("result for %s at depth %d, expected %s at 1", r.M, r.Depth, bad)
+	}
+	if n := r.Trace.Len(); n != 2 {
+		t.Errorf("trace length %d, expected 2", n)
+	}
+	if errs := r.Trace.Verify(trans); len(errs) != 0 {
+		t.Errorf("trace: %v", errs)
+	}
+	// a latch which stays false, with a bad state over an input.
+	trans = logic.NewS()
+	in = trans.Lit()
+	l = trans.Latch(trans.F)
+	trans.SetNext(l, trans.And(l, in))
+	bad = trans.And(l, in)
+	mc = New(trans, bad)
+	if res, err := mc.Try(); res != -1 || err != nil {
+		t.Fatalf("got %d %v, expected -1", res, err)
+	}
+	inv := mc.Invariant()
+	if fs := inv.Check(trans, bad, time.Second); len(fs) != 0 {
+		t.Errorf("invariant %v: %v", inv, fs[0])
+	}
+}
+
+// TestIicInitTrace checks the traces of bad states reachable at depths 0
+// and 1, which are found before the main loop of iic.
+func TestIicInitTrace(t *testing.T) {
+	for d := 0; d < 2; d++ {
+		trans := logic.NewS()
+		l := trans.Latch(trans.T)
+		m := trans.Latch(trans.F)
+		trans.SetNext(l, l)
+		trans.SetNext(m, l)
+		bad := l
+		if d == 1 {
+			bad = m
+		}
+		mc := New(trans.Copy(), bad)
+		if res, err := mc.Try(); res != 1 || err != nil {
+			t.Fatalf("depth %d: got %d %v, expected 1", d, res, err)
+		}
+		out := &reach.Output{}
+		if err := mc.FillOutput(out); err != nil {
+			t.Fatalf("depth %d: %s", d, err)
+		}
+		r := out.Results()[0]
+		if r.Depth != d || r.Trace.Len() != d+1 {
+			t.Errorf("depth %d: got depth %d trace length %d", d, r.Depth, r.Trace.Len())
+		}
+		if errs := r.Trace.Verify(trans); len(errs) != 0 {
+			t.Errorf("depth %d: trace: %v", d, errs)
+		}
+	}
+}
+
+func TestIicStopFrames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	N := 10
+	trans := logic.NewS()
+	in := trans.Lit()
+	carry := trans.T
+	for i := 0; i < N; i++ {
+		m := trans.Latch(trans.F)
+		trans.SetNext(m, trans.Choice(trans.And(carry, in), m.Not(), m))
+		carry = trans.And(carry, m)
+	}
+	out, err := reach.MakeOutputSys(trans, "iic", dir, carry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := New(trans, carry)
+	mc.maxDepth = 4
+	if res, err := mc.Try(); res != 0 || err != nil {
+		t.Fatalf("got %d %v, expected 0", res, err)
+	}
+	mc.Stop()
+	if err := mc.FillOutput(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Store(); err != nil {
+		t.Fatal(err)
+	}
+	// the frames hold in the initial state, where all latches are false.
+	var c []z.Lit
+	for _, m := range mc.rResult.Frames {
+		if m != z.LitNull {
+			c = append(c, m)
+			continue
+		}
+		ok := false
+		for _, n := range c {
+			ok = ok || !n.IsPos()
+		}
+		if !ok {
+			t.Errorf("frame clause %v excludes the initial state", c)
+		}
+		c = c[:0]
+	}
+	o, err := reach.OpenOutput(out.RootDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	rs := o.Results()
+	if len(rs) != 1 || rs[0].IsSolved() || rs[0].FrameClauses == 0 {
+		t.Fatalf("unexpected results %v", rs)
+	}
+	if _, err := os.Stat(o.FramesPath(0)); err != nil {
+		t.Error(err)
+	}
+	man, err := o.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(man.Results) != 1 || len(man.Results[0].Files) != 2 {
+		t.Errorf("frames not listed in manifest: %+v", man)
+	}
+	if errs := o.Validate(); len(errs) != 0 {
+		t.Error(errs)
+	}
+}
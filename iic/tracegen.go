@@ -34,13 +34,26 @@ type traceGen struct {
 	primer    *reach.Primer
 	obs       *obs.Set
 	init, bad z.Lit
+	inBad     z.Lit // if not z.LitNull, bad is delayed inBad.
 	badPrime  z.Lit
 	sat       *satmon
 	hd        obs.Id
 }
 
+// build builds the trace from the initial state to the bad state through
+// the proof obligations starting at g.hd.
+//
+// If the bad state is delayed, the trace is over the original transition
+// system, without the latch of the delayed bad state, and it ends one step
+// earlier, when g.inBad holds.
 func (g *traceGen) build() (*reach.Trace, error) {
-	trace := reach.NewTraceLen(g.trans, g.orgLen, g.bad)
+	orgLen, watch := g.orgLen, g.bad
+	if g.inBad != z.LitNull {
+		// the original variables precede the latch of the delayed bad state.
+		orgLen, watch = int(g.bad.Var()), g.inBad
+	}
+	trace := reach.NewTraceLen(g.trans, orgLen, watch)
+	var last []bool // the values of the last step, appended when it is not.
 	obA := g.hd
 	vals := make([]bool, g.trans.Len()) // latch values
 	// first set initial states.
@@ -78,15 +91,18 @@ func (g *traceGen) build() (*reach.Trace, error) {
 		res := g.sat.Try()
 		switch res {
 		case 0:
-			return trace, fmt.Errorf("ErrTraceBuildTimeout")
+			return trace, fmt.Errorf("%w: building trace", reach.ErrTimeout)
 		case -1:
-			return nil, fmt.Errorf("ErrInternalBadTrace: %v", g.sat.Why(nil))
+			return nil, fmt.Errorf("%w: bad trace: %v", reach.ErrInternal, g.sat.Why(nil))
 		}
 		for i := range vals {
 			m := z.Var(i).Pos()
 			vals[i] = g.sat.Value(m)
 		}
-		trace.Append(vals[:g.orgLen])
+		if last != nil {
+			trace.Append(last)
+		}
+		last = append(last[:0], vals[:orgLen]...)
 		obA = obB
 		if obA == 0 {
 			break
@@ -104,5 +120,8 @@ func (g *traceGen) build() (*reach.Trace, error) {
 			g.sat.Assume(m)
 		}
 	}
+	if g.inBad == z.LitNull && last != nil {
+		trace.Append(last)
+	}
 	return trace, nil
 }
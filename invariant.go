@@ -0,0 +1,475 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-air/gini"
+	"github.com/go-air/gini/dimacs"
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/logic/aiger"
+	"github.com/go-air/gini/z"
+)
+
+// Invariant is an invariant in cnf, given as a list of clauses each
+// terminated by 0.  The literals of an Invariant are literals of a logic.S,
+// and may be any literals, not only latches.
+//
+// Invariant implements inter.Adder, so clauses may be added with Add as
+// with a sat solver.
+type Invariant []z.Lit
+
+// Add adds a literal, or a clause terminator 0, to `inv`.
+func (inv *Invariant) Add(m z.Lit) {
+	*inv = append(*inv, m)
+}
+
+// Len returns the number of clauses in `inv`.
+func (inv Invariant) Len() int {
+	n := 0
+	for _, m := range inv {
+		if m == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Forall calls `f` for every clause of `inv` with the index and the
+// literals of the clause.  `f` should not retain or modify `c`.  Literals
+// after the last 0 are ignored.
+func (inv Invariant) Forall(f func(i int, c []z.Lit)) {
+	start, j := 0, 0
+	for i, m := range inv {
+		if m != 0 {
+			continue
+		}
+		f(j, inv[start:i])
+		j++
+		start = i + 1
+	}
+}
+
+// IsTerminated returns whether every clause of `inv` is terminated by 0.
+func (inv Invariant) IsTerminated() bool {
+	n := len(inv)
+	return n == 0 || inv[n-1] == 0
+}
+
+// Size returns the size of `inv`.
+func (inv Invariant) Size() InvariantSize {
+	sz := InvariantSize{}
+	for _, m := range inv {
+		if m == 0 {
+			sz.Clauses++
+		} else {
+			sz.Lits++
+		}
+	}
+	return sz
+}
+
+// InvariantSize gives the number of clauses and literals of an Invariant.
+type InvariantSize struct {
+	Clauses int
+	Lits    int
+}
+
+func (sz InvariantSize) String() string {
+	return fmt.Sprintf("%d clauses, %d literals", sz.Clauses, sz.Lits)
+}
+
+// Simplify returns an equivalent invariant in which the literals of each
+// clause are sorted and distinct, and without tautologies, duplicate
+// clauses or clauses subsumed by other clauses.  Simplify does not use a sat
+// solver, and so does not remove clauses implied by the circuit.
+func (inv Invariant) Simplify() Invariant {
+	var cs [][]z.Lit
+	inv.Forall(func(_ int, c []z.Lit) {
+		d := append([]z.Lit{}, c...)
+		sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+		k := 0
+		for i, m := range d {
+			if i > 0 && m == d[k-1] {
+				continue
+			}
+			if i > 0 && m == d[k-1].Not() {
+				return // tautology
+			}
+			d[k] = m
+			k++
+		}
+		cs = append(cs, d[:k])
+	})
+	sort.SliceStable(cs, func(i, j int) bool { return len(cs[i]) < len(cs[j]) })
+	var res Invariant
+	var kept [][]z.Lit
+	for _, c := range cs {
+		subsumed := false
+		for _, d := range kept {
+			if subsumes(d, c) {
+				subsumed = true
+				break
+			}
+		}
+		if subsumed {
+			continue
+		}
+		kept = append(kept, c)
+		res = append(res, c...)
+		res = append(res, 0)
+	}
+	return res
+}
+
+// subsumes returns whether sorted clause `c` is a subset of sorted clause
+// `d`.
+func subsumes(c, d []z.Lit) bool {
+	j := 0
+	for _, m := range c {
+		for j < len(d) && d[j] < m {
+			j++
+		}
+		if j == len(d) || d[j] != m {
+			return false
+		}
+		j++
+	}
+	return true
+}
+
+// InvariantFailure describes a failed check of an Invariant.
+type InvariantFailure struct {
+	Err    error   // ErrInvariantInit, ErrInvariantNotInductive, ErrInvariantUnsafe, ErrInvariantFormat or ErrTimeout.
+	Bad    z.Lit   // the bad state literal.
+	Clause int     // the index of the failing clause, or -1.
+	Lits   []z.Lit // the literals of the failing clause, if any.
+}
+
+func (f *InvariantFailure) Error() string {
+	if f.Clause < 0 {
+		return fmt.Sprintf("%s[%s]", f.Err, f.Bad)
+	}
+	return fmt.Sprintf("%s[%s]: clause %d %v", f.Err, f.Bad, f.Clause, f.Lits)
+}
+
+// Unwrap returns f.Err.
+func (f *InvariantFailure) Unwrap() error {
+	return f.Err
+}
+
+// Check checks that `inv` is an inductive invariant showing `bad` is
+// unreachable in `s`.  That is, Check checks
+//
+//  1. initiation: every initial state satisfies every clause;
+//  2. consecution: every clause holds in every successor of a state
+//     satisfying `inv`; and
+//  3. safety: no state satisfying `inv` satisfies `bad`.
+//
+// The clauses of `inv` and `bad` may depend on the inputs of `s`, in which
+// case consecution is checked with distinct inputs for the successor, so
+// that clauses hold for all inputs in every reachable state.
+//
+// Check returns the list of failures found, which is empty iff all checks
+// succeed within `dur`.  If time runs out, the last failure has Err
+// ErrTimeout.  Check works on a copy of `s`, which is not modified.
+func (inv Invariant) Check(s *logic.S, bad z.Lit, dur time.Duration) []*InvariantFailure {
+	return inv.CheckParallel(s, bad, dur, 1)
+}
+
+// minParClauses is the least number of clauses whose consecution is checked
+// by each solver in CheckParallel.
+const minParClauses = 64
+
+// CheckParallel is like Check, but splits the consecution checks among up to
+// `j` sat solvers running concurrently.  Each solver checks at least
+// minParClauses clauses, so small invariants are checked by one solver.
+// Failures are returned in the same order as by Check.
+func (inv Invariant) CheckParallel(s *logic.S, bad z.Lit, dur time.Duration, j int) []*InvariantFailure {
+	deadline := time.Now().Add(dur)
+	if !inv.IsTerminated() {
+		return []*InvariantFailure{{Err: ErrInvariantFormat, Bad: bad, Clause: -1}}
+	}
+	ps := make([]z.Lit, 0, len(inv)+1)
+	ps = append(ps, bad)
+	for _, m := range inv {
+		if m != 0 {
+			ps = append(ps, m)
+		}
+	}
+	s = s.Copy()
+	pri := newInputPrimer(s, ps...)
+	sat := gini.New()
+	s.ToCnf(sat)
+	var fs []*InvariantFailure
+	fail := func(err error, i int, c []z.Lit) {
+		f := &InvariantFailure{Err: err, Bad: bad, Clause: i}
+		if c != nil {
+			f.Lits = append([]z.Lit{}, c...)
+		}
+		fs = append(fs, f)
+	}
+	timedOut := false
+	try := func() int {
+		res := sat.Try(time.Until(deadline))
+		if res == 0 && !timedOut {
+			timedOut = true
+			fail(ErrTimeout, -1, nil)
+		}
+		return res
+	}
+	assumeInit := func() {
+		for _, m := range s.Latches {
+			switch s.Init(m) {
+			case s.T:
+				sat.Assume(m)
+			case s.F:
+				sat.Assume(m.Not())
+			}
+		}
+	}
+
+	// initiation
+	inv.Forall(func(i int, c []z.Lit) {
+		if timedOut {
+			return
+		}
+		assumeInit()
+		for _, m := range c {
+			sat.Assume(m.Not())
+		}
+		if try() == 1 {
+			fail(ErrInvariantInit, i, c)
+		}
+	})
+	if timedOut {
+		return fs
+	}
+	for _, m := range inv {
+		sat.Add(m)
+	}
+
+	// safety
+	sat.Assume(bad)
+	switch try() {
+	case 0:
+		return fs
+	case 1:
+		fail(ErrInvariantUnsafe, -1, nil)
+	}
+
+	// consecution
+	n := inv.Len()
+	if j > n/minParClauses {
+		j = n / minParClauses
+	}
+	if j <= 1 {
+		inv.Forall(func(i int, c []z.Lit) {
+			if timedOut {
+				return
+			}
+			for _, m := range c {
+				sat.Assume(pri.Prime(m).Not())
+			}
+			if try() == 1 {
+				fail(ErrInvariantNotInductive, i, c)
+			}
+		})
+		return fs
+	}
+	cls := make([][]z.Lit, 0, n)
+	inv.Forall(func(_ int, c []z.Lit) {
+		cls = append(cls, c)
+	})
+	res := make([]int, n)
+	var wg sync.WaitGroup
+	for w := 0; w < j; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			wsat := gini.New()
+			s.ToCnf(wsat)
+			for _, m := range inv {
+				wsat.Add(m)
+			}
+			for i := w; i < n; i += j {
+				for _, m := range cls[i] {
+					wsat.Assume(pri.Prime(m).Not())
+				}
+				res[i] = wsat.Try(time.Until(deadline))
+				if res[i] == 0 {
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	for i, r := range res {
+		if r == 1 {
+			fail(ErrInvariantNotInductive, i, cls[i])
+		}
+	}
+	for _, r := range res {
+		if r == 0 {
+			fail(ErrTimeout, -1, nil)
+			break
+		}
+	}
+	return fs
+}
+
+// CheckInvariant checks that `inv`, a cnf with clauses terminated by 0 over
+// literals in `s`, is an inductive invariant showing `bad` is unreachable,
+// as in Invariant.Check.
+//
+// CheckInvariant returns the list of errors found, which is empty iff all
+// checks succeed within `dur`.  The errors are of type *InvariantFailure.
+func CheckInvariant(s *logic.S, bad z.Lit, inv []z.Lit, dur time.Duration) []error {
+	return failureErrors(Invariant(inv).Check(s, bad, dur))
+}
+
+func failureErrors(fs []*InvariantFailure) []error {
+	if len(fs) == 0 {
+		return nil
+	}
+	errs := make([]error, len(fs))
+	for i, f := range fs {
+		errs[i] = f
+	}
+	return errs
+}
+
+// WriteDimacs writes `inv` in dimacs cnf format, with variables numbered
+// as in the logic.S of its literals.
+func (inv Invariant) WriteDimacs(w io.Writer) error {
+	nv := 0
+	for _, m := range inv {
+		if mv := int(m.Var()); m != 0 && mv > nv {
+			nv = mv
+		}
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "p cnf %d %d\n", nv, inv.Len())
+	for _, m := range inv {
+		if m == 0 {
+			bw.WriteString("0\n")
+		} else {
+			fmt.Fprintf(bw, "%s ", m)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteAiger writes `inv` as a binary combinational aiger with one input for
+// each latch of `s`, in order, and a single output which is true exactly
+// when the latches satisfy `inv`.  This is the format read by AigerInvariant.
+// The literals of `inv` must not depend on the inputs of `s`.
+func (inv Invariant) WriteAiger(w io.Writer, s *logic.S) error {
+	d := logic.NewS()
+	vmap := make([]z.Lit, s.Len())
+	vmap[s.T.Var()] = d.T
+	for _, m := range s.Latches {
+		vmap[m.Var()] = d.Lit()
+	}
+	var err error
+	var get func(m z.Lit) z.Lit
+	get = func(m z.Lit) z.Lit {
+		v := m.Var()
+		if vmap[v] == z.LitNull {
+			switch s.Type(m) {
+			case logic.SAnd:
+				a, b := s.Ins(v.Pos())
+				vmap[v] = d.And(get(a), get(b))
+			default:
+				err = fmt.Errorf("%w: invariant depends on input %s", ErrInvariantFormat, m)
+				vmap[v] = d.F
+			}
+		}
+		return mapLit(vmap, m)
+	}
+	acc := d.T
+	inv.Forall(func(_ int, c []z.Lit) {
+		cl := d.F
+		for _, m := range c {
+			cl = d.Or(cl, get(m))
+		}
+		acc = d.And(acc, cl)
+	})
+	if err != nil {
+		return err
+	}
+	return WriteAiger(w, d, []z.Lit{acc}, nil)
+}
+
+type dimacsVis struct {
+	ms []z.Lit
+}
+
+func (d *dimacsVis) Add(m z.Lit) {
+	d.ms = append(d.ms, m)
+}
+func (d *dimacsVis) Init(v, c int) {
+}
+func (d *dimacsVis) Eof() {
+}
+
+// ReadDimacsInvariant reads an invariant in dimacs cnf format, as written by
+// Invariant.WriteDimacs.
+func ReadDimacsInvariant(r io.Reader) (Invariant, error) {
+	vis := &dimacsVis{}
+	if err := dimacs.ReadCnf(r, vis); err != nil {
+		return nil, err
+	}
+	return vis.ms, nil
+}
+
+// ReadAigerInvariant reads an invariant for `s` as a binary combinational
+// aiger, as written by Invariant.WriteAiger, adding it to `s` as with
+// AigerInvariant.  The returned Invariant has a single unit clause.
+func ReadAigerInvariant(r io.Reader, s *logic.S) (Invariant, error) {
+	g, err := aiger.ReadBinary(r)
+	if err != nil {
+		return nil, err
+	}
+	m, err := AigerInvariant(s, g)
+	if err != nil {
+		return nil, err
+	}
+	return Invariant{m, 0}, nil
+}
+
+// AigerInvariant adds the invariant given by the combinational aiger `inv` to
+// `s` and returns its literal in `s`.
+//
+// `inv` should have no latches, one input for every latch in `s` in the same
+// order, and a single output (or bad state literal) defining the invariant
+// over these inputs.  This is the format in which ABC writes inductive
+// invariants.
+func AigerInvariant(s *logic.S, inv *aiger.T) (z.Lit, error) {
+	is := inv.Sys()
+	if len(is.Latches) != 0 {
+		return z.LitNull, fmt.Errorf("%w: invariant aiger has %d latches", ErrInvariantFormat, len(is.Latches))
+	}
+	if len(inv.Inputs) != len(s.Latches) {
+		return z.LitNull, fmt.Errorf("%w: invariant aiger has %d inputs for %d latches", ErrInvariantFormat,
+			len(inv.Inputs), len(s.Latches))
+	}
+	outs := AigerBad(inv)
+	if len(outs) != 1 {
+		return z.LitNull, fmt.Errorf("%w: invariant aiger has %d outputs", ErrInvariantFormat, len(outs))
+	}
+	vmap := make([]z.Lit, is.Len())
+	vmap[is.T.Var()] = s.T
+	for i, m := range inv.Inputs {
+		vmap[m.Var()] = s.Latches[i]
+	}
+	importAnds(s, is, vmap)
+	return mapLit(vmap, outs[0]), nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/z"
+)
+
+// genSafe generates a circuit with unreachable bad state a&b, where b is
+// constant false and c is a free latch.
+func genSafe() (s *logic.S, a, b, c, bad z.Lit) {
+	s = logic.NewS()
+	in := s.Lit()
+	a, b, c = s.Latch(s.F), s.Latch(s.F), s.Latch(s.F)
+	s.SetNext(a, s.Choice(in, a, s.And(a, b)))
+	s.SetNext(b, b)
+	s.SetNext(c, in)
+	return s, a, b, c, s.And(a, b)
+}
+
+func TestCheckInvariant(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		inv  func(a, b, c, bad z.Lit) []z.Lit
+		err  error // nil if ok
+	}{
+		{"notb", func(a, b, c, bad z.Lit) []z.Lit { return []z.Lit{b.Not(), 0} }, nil},
+		{"notbad", func(a, b, c, bad z.Lit) []z.Lit { return []z.Lit{bad.Not(), 0} }, nil},
+		{"init", func(a, b, c, bad z.Lit) []z.Lit { return []z.Lit{b, 0} }, ErrInvariantInit},
+		{"safety", func(a, b, c, bad z.Lit) []z.Lit { return nil }, ErrInvariantUnsafe},
+		{"nota", func(a, b, c, bad z.Lit) []z.Lit { return []z.Lit{a.Not(), 0, bad.Not(), 0} }, nil},
+		{"consecution", func(a, b, c, bad z.Lit) []z.Lit { return []z.Lit{c.Not(), 0, b.Not(), 0} }, ErrInvariantNotInductive},
+		{"unterminated", func(a, b, c, bad z.Lit) []z.Lit { return []z.Lit{b.Not()} }, ErrInvariantFormat},
+	} {
+		s, a, b, c, bad := genSafe()
+		errs := CheckInvariant(s, bad, tc.inv(a, b, c, bad), time.Second)
+		if tc.err == nil && len(errs) != 0 {
+			t.Errorf("%s: unexpected errors %v", tc.name, errs)
+		}
+		if tc.err != nil && (len(errs) == 0 || !errors.Is(errs[0], tc.err)) {
+			t.Errorf("%s: expected %s, got %v", tc.name, tc.err, errs)
+		}
+	}
+}
+
+// genInputBad generates a circuit with a latch l following input in, and
+// the bad state l&!in over the input, which is reachable at depth 1.
+func genInputBad() (s *logic.S, in, l, bad z.Lit) {
+	s = logic.NewS()
+	in = s.Lit()
+	l = s.Latch(s.F)
+	s.SetNext(l, in)
+	return s, in, l, s.And(l, in.Not())
+}
+
+func TestCheckInvariantInputs(t *testing.T) {
+	s, _, l, bad := genInputBad()
+	n := s.Len()
+	errs := CheckInvariant(s, bad, []z.Lit{bad.Not(), 0}, time.Second)
+	if len(errs) == 0 || !errors.Is(errs[0], ErrInvariantNotInductive) {
+		t.Errorf("expected %s for reachable bad over inputs, got %v", ErrInvariantNotInductive, errs)
+	}
+	if _, err := MinimizeInvariant(s, bad, Invariant{bad.Not(), 0}, time.Second); err == nil {
+		t.Errorf("minimized invariant for reachable bad over inputs")
+	}
+	if s.Len() != n {
+		t.Errorf("checking added %d nodes", s.Len()-n)
+	}
+
+	// l is constant false, and !(l&in) holds for all inputs only with !l.
+	s = logic.NewS()
+	in := s.Lit()
+	l = s.Latch(s.F)
+	s.SetNext(l, l)
+	bad = s.And(l, in)
+	if errs := CheckInvariant(s, bad, []z.Lit{bad.Not(), 0}, time.Second); len(errs) == 0 {
+		t.Errorf("expected errors for !bad without !l")
+	}
+	if errs := CheckInvariant(s, bad, []z.Lit{l.Not(), 0, bad.Not(), 0}, time.Second); len(errs) != 0 {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
+
+func TestInvariantCheckClause(t *testing.T) {
+	s, a, b, c, bad := genSafe()
+	fs := Invariant{b.Not(), 0, a, 0}.Check(s, bad, time.Second)
+	if len(fs) == 0 || fs[0].Clause != 1 || !errors.Is(fs[0], ErrInvariantInit) {
+		t.Errorf("expected initiation failure at clause 1, got %v", fs)
+	}
+	fs = Invariant{bad.Not(), 0, c.Not(), 0, b.Not(), 0}.Check(s, bad, time.Second)
+	if len(fs) != 1 || fs[0].Clause != 1 || !errors.Is(fs[0], ErrInvariantNotInductive) {
+		t.Errorf("expected consecution failure at clause 1, got %v", fs)
+	}
+	if len(fs) != 0 && (len(fs[0].Lits) != 1 || fs[0].Lits[0] != c.Not()) {
+		t.Errorf("expected failing clause [%s], got %v", c.Not(), fs[0].Lits)
+	}
+}
+
+func TestInvariantSimplify(t *testing.T) {
+	_, a, b, c, _ := genSafe()
+	inv := Invariant{c, a, c, 0, a, c, 0, b, b.Not(), 0, a, b, c, 0, b.Not(), 0}
+	sim := inv.Simplify()
+	if sim.Len() != 2 {
+		t.Fatalf("expected 2 clauses, got %v", sim)
+	}
+	var cs [][]z.Lit
+	sim.Forall(func(i int, c []z.Lit) {
+		cs = append(cs, append([]z.Lit{}, c...))
+	})
+	if len(cs[0]) != 1 || cs[0][0] != b.Not() {
+		t.Errorf("expected unit clause %s first, got %v", b.Not(), cs[0])
+	}
+	if len(cs[1]) != 2 || cs[1][0] != a || cs[1][1] != c {
+		t.Errorf("expected clause [%s %s], got %v", a, c, cs[1])
+	}
+}
+
+func TestInvariantReadWrite(t *testing.T) {
+	s, a, b, _, bad := genSafe()
+	inv := Invariant{a.Not(), 0, bad.Not(), 0}
+	buf := &bytes.Buffer{}
+	if err := inv.WriteDimacs(buf); err != nil {
+		t.Fatal(err)
+	}
+	d, err := ReadDimacsInvariant(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Size() != inv.Size() {
+		t.Errorf("dimacs size %s, expected %s", d.Size(), inv.Size())
+	}
+	if fs := d.Check(s, bad, time.Second); len(fs) != 0 {
+		t.Errorf("dimacs: %v", fs)
+	}
+	buf.Reset()
+	if err := inv.WriteAiger(buf, s); err != nil {
+		t.Fatal(err)
+	}
+	g, err := ReadAigerInvariant(buf, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs := g.Check(s, bad, time.Second); len(fs) != 0 {
+		t.Errorf("aiger: %v", fs)
+	}
+	buf.Reset()
+	if err := (Invariant{b.Not(), 0}).WriteAiger(buf, s); err != nil {
+		t.Fatal(err)
+	}
+	if err := (Invariant{s.And(a, sysInputs(s)[0]), 0}).WriteAiger(buf, s); !errors.Is(err, ErrInvariantFormat) {
+		t.Errorf("expected %s writing invariant over inputs, got %v", ErrInvariantFormat, err)
+	}
+}
+
+func TestInvariantCheckParallel(t *testing.T) {
+	n := 24
+	s, bad := genRing(n, 3)
+	var inv Invariant
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if inv.Len() == 100 {
+				inv = append(inv, s.Latches[1].Not(), 0)
+			}
+			inv = append(inv, s.Latches[i].Not(), s.Latches[j].Not(), 0)
+		}
+	}
+	fs := inv.CheckParallel(s, bad, 10*time.Second, 4)
+	if len(fs) != 1 || fs[0].Clause != 100 || !errors.Is(fs[0], ErrInvariantNotInductive) {
+		t.Errorf("expected consecution failure at clause 100, got %v", fs)
+	}
+	ok := append(Invariant{}, inv[:300]...)
+	ok = append(ok, inv[302:]...)
+	if fs := ok.CheckParallel(s, bad, 10*time.Second, 4); len(fs) != 0 {
+		t.Errorf("unexpected failures %v", fs)
+	}
+}
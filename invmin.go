@@ -0,0 +1,259 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-air/gini"
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/z"
+)
+
+// MinimizeInvariant returns an inductive invariant showing `bad` is
+// unreachable in `s` whose clauses are a subset of those of `inv`, each
+// possibly with some literals removed.  `inv` is a cnf with clauses
+// terminated by 0, as for CheckInvariant.
+//
+// First, clauses are removed: for each clause, the largest subset of the
+// other clauses which is inductive is computed, and if it implies `bad` is
+// false, it replaces the invariant.  Then literals are removed from the
+// remaining clauses whenever the resulting clause still holds initially and
+// is inductive relative to the others.  Each step preserves the invariant,
+// so the result is minimal in the sense that no single clause or literal
+// can be removed.
+//
+// If no initiated subset of the clauses of `inv` is an inductive invariant
+// for `bad`, then MinimizeInvariant returns an error.  If time runs out after `dur`, MinimizeInvariant
+// returns the partially minimized invariant, which is valid, together with
+// ErrTimeout.  As with Invariant.Check, `inv` and `bad` may depend on the
+// inputs of `s`.  MinimizeInvariant works on a copy of `s`, which is not
+// modified.
+func MinimizeInvariant(s *logic.S, bad z.Lit, inv Invariant, dur time.Duration) (Invariant, error) {
+	if !inv.IsTerminated() {
+		return nil, fmt.Errorf("%w[%s]: not null terminated", ErrInvariantFormat, bad)
+	}
+	ps := make([]z.Lit, 0, len(inv)+1)
+	ps = append(ps, bad)
+	for _, m := range inv {
+		if m != 0 {
+			ps = append(ps, m)
+		}
+	}
+	s = s.Copy()
+	im := &invMin{
+		s:        s,
+		bad:      bad,
+		pri:      newInputPrimer(s, ps...),
+		sat:      gini.New(),
+		deadline: time.Now().Add(dur),
+		nextVar:  z.Var(s.Len())}
+	s.ToCnf(im.sat)
+	inv.Forall(func(_ int, c []z.Lit) {
+		im.add(append([]z.Lit{}, c...))
+	})
+	for _, c := range im.cls {
+		switch im.initiates(c) {
+		case 0:
+			return nil, ErrTimeout
+		case 1:
+			return nil, fmt.Errorf("%w[%s]: %v", ErrInvariantInit, bad, c)
+		}
+	}
+	switch im.reduce(-1) {
+	case 0:
+		return nil, ErrTimeout
+	case 1:
+		return nil, fmt.Errorf("%w[%s]", ErrInvariantNotInductive, bad)
+	}
+
+	// remove clauses
+	for i := len(im.cls) - 1; i >= 0; i-- {
+		if !im.on[i] {
+			continue
+		}
+		if im.reduce(i) == 0 {
+			return im.result(), ErrTimeout
+		}
+	}
+	// remove literals
+	for i := range im.cls {
+		for j := 0; im.on[i] && j < len(im.cls[i]) && len(im.cls[i]) > 1; {
+			res, err := im.shrink(i, j)
+			if err != nil {
+				return im.result(), err
+			}
+			if !res {
+				j++
+			}
+		}
+	}
+	return im.result(), nil
+}
+
+// invMin holds the state of MinimizeInvariant.  Each clause is added to the
+// solver guarded by an activation literal, so that the invariant can be
+// changed incrementally.
+type invMin struct {
+	s        *logic.S
+	bad      z.Lit
+	pri      *Primer
+	sat      *gini.Gini
+	deadline time.Time
+	nextVar  z.Var
+	cls      [][]z.Lit
+	acts     []z.Lit
+	on       []bool // whether the clause is in the invariant.
+}
+
+func (im *invMin) add(c []z.Lit) {
+	im.cls = append(im.cls, c)
+	im.acts = append(im.acts, im.guard(c))
+	im.on = append(im.on, true)
+}
+
+// guard adds `c` to the solver guarded by a new activation literal, which
+// is returned.
+func (im *invMin) guard(c []z.Lit) z.Lit {
+	act := im.nextVar.Pos()
+	im.nextVar++
+	im.sat.Add(act.Not())
+	for _, m := range c {
+		im.sat.Add(m)
+	}
+	im.sat.Add(0)
+	return act
+}
+
+func (im *invMin) kill(act z.Lit) {
+	im.sat.Add(act.Not())
+	im.sat.Add(0)
+}
+
+func (im *invMin) try() int {
+	return im.sat.Try(time.Until(im.deadline))
+}
+
+// assume assumes the clauses marked in `on`, together with the clause
+// guarded by `extra` if it is not z.LitNull.
+func (im *invMin) assume(on []bool, extra z.Lit) {
+	for i, act := range im.acts {
+		if on[i] {
+			im.sat.Assume(act)
+		}
+	}
+	if extra != z.LitNull {
+		im.sat.Assume(extra)
+	}
+}
+
+// initiates returns -1 if every initial state satisfies `c`, 1 if not and 0
+// on timeout.
+func (im *invMin) initiates(c []z.Lit) int {
+	s := im.s
+	for _, m := range s.Latches {
+		switch s.Init(m) {
+		case s.T:
+			im.sat.Assume(m)
+		case s.F:
+			im.sat.Assume(m.Not())
+		}
+	}
+	for _, m := range c {
+		im.sat.Assume(m.Not())
+	}
+	return im.try()
+}
+
+// consecutive returns -1 if `c` holds in every successor of a state
+// satisfying the clauses marked in `on` and the clause guarded by `extra`,
+// 1 if not and 0 on timeout.
+func (im *invMin) consecutive(on []bool, extra z.Lit, c []z.Lit) int {
+	im.assume(on, extra)
+	for _, m := range c {
+		im.sat.Assume(im.pri.Prime(m).Not())
+	}
+	return im.try()
+}
+
+// reduce computes the largest inductive subset of the invariant without
+// clause `skip` (which may be -1 to keep all clauses).  If that subset
+// implies `bad` is false, then it becomes the invariant and reduce returns
+// -1.  Otherwise, reduce returns 1, or 0 on timeout.
+func (im *invMin) reduce(skip int) int {
+	on := make([]bool, len(im.on))
+	copy(on, im.on)
+	if skip >= 0 {
+		on[skip] = false
+	}
+	for changed := true; changed; {
+		changed = false
+		for i, c := range im.cls {
+			if !on[i] {
+				continue
+			}
+			switch im.consecutive(on, z.LitNull, c) {
+			case 0:
+				return 0
+			case 1:
+				on[i] = false
+				changed = true
+			}
+		}
+	}
+	im.assume(on, z.LitNull)
+	im.sat.Assume(im.bad)
+	if res := im.try(); res != -1 {
+		return res
+	}
+	for i, act := range im.acts {
+		if im.on[i] && !on[i] {
+			im.kill(act)
+		}
+	}
+	im.on = on
+	return -1
+}
+
+// shrink tries to remove literal j from clause i, returning whether it did.
+func (im *invMin) shrink(i, j int) (bool, error) {
+	c := im.cls[i]
+	d := make([]z.Lit, 0, len(c)-1)
+	d = append(d, c[:j]...)
+	d = append(d, c[j+1:]...)
+	switch im.initiates(d) {
+	case 0:
+		return false, ErrTimeout
+	case 1:
+		return false, nil
+	}
+	act := im.guard(d)
+	im.on[i] = false
+	res := im.consecutive(im.on, act, d)
+	im.on[i] = true
+	switch res {
+	case 0:
+		im.kill(act)
+		return false, ErrTimeout
+	case 1:
+		im.kill(act)
+		return false, nil
+	}
+	im.kill(im.acts[i])
+	im.cls[i], im.acts[i] = d, act
+	return true, nil
+}
+
+func (im *invMin) result() Invariant {
+	var res Invariant
+	for i, c := range im.cls {
+		if !im.on[i] {
+			continue
+		}
+		res = append(res, c...)
+		res = append(res, 0)
+	}
+	return res
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/z"
+)
+
+// genRing generates a ring of n latches holding a single token which
+// rotates on input, with bad state that the first and k'th latch both hold
+// a token.
+func genRing(n, k int) (s *logic.S, bad z.Lit) {
+	s = logic.NewS()
+	in := s.Lit()
+	for i := 0; i < n; i++ {
+		init := s.F
+		if i == 0 {
+			init = s.T
+		}
+		s.Latch(init)
+	}
+	for i, m := range s.Latches {
+		s.SetNext(m, s.Choice(in, s.Latches[(i+n-1)%n], m))
+	}
+	return s, s.And(s.Latches[0], s.Latches[k])
+}
+
+func TestMinimizeInvariant(t *testing.T) {
+	n, k := 6, 3
+	s, bad := genRing(n, k)
+	var inv []z.Lit
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			inv = append(inv, s.Latches[i].Not(), s.Latches[j].Not(), 0)
+		}
+	}
+	inv = append(inv, bad.Not(), 0)
+	inv = append(inv, s.Latches[0].Not(), s.Latches[1].Not(), s.Latches[k].Not(), 0)
+	min, err := MinimizeInvariant(s, bad, inv, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sz := min.Size(); sz.Clauses != n-k || sz.Lits != 2*(n-k) {
+		t.Errorf("minimized to %s: %v", sz, min)
+	}
+	s, bad = genRing(n, k)
+	if errs := CheckInvariant(s, bad, min, time.Second); len(errs) != 0 {
+		t.Errorf("minimized invariant: %v", errs)
+	}
+
+	s, a, b, c, bad := genSafe()
+	min, err = MinimizeInvariant(s, bad, []z.Lit{b.Not(), a.Not(), 0}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(min) != 2 {
+		t.Errorf("minimized to %v", min)
+	}
+	if errs := CheckInvariant(s, bad, min, time.Second); len(errs) != 0 {
+		t.Errorf("minimized invariant: %v", errs)
+	}
+	if _, err := MinimizeInvariant(s, bad, []z.Lit{c.Not(), 0}, time.Second); err == nil {
+		t.Errorf("no error minimizing invalid invariant")
+	}
+}
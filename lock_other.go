@@ -0,0 +1,19 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package reach
+
+// lockDir does not lock on this platform, so concurrent processes should
+// not store results in the same directory.
+func lockDir(d string) (func(), error) {
+	return func() {}, nil
+}
+
+// syncDir does nothing on this platform, where directories cannot be
+// flushed.
+func syncDir(d string) error {
+	return nil
+}
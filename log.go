@@ -0,0 +1,50 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+// Logger receives diagnostic messages from the reach packages.  It is
+// satisfied by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(format string, v ...interface{}) {}
+
+type loggerBox struct {
+	Logger
+}
+
+var logger atomic.Value
+
+func init() {
+	logger.Store(loggerBox{stdLogger{}})
+}
+
+// SetLogger sets the logger for diagnostics from the reach packages.  The
+// default logs with the standard "log" package.  If `l` is nil, diagnostics
+// are discarded.  SetLogger is safe for concurrent use.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = nopLogger{}
+	}
+	logger.Store(loggerBox{l})
+}
+
+// Logf logs a diagnostic message with the logger set by SetLogger.
+func Logf(format string, v ...interface{}) {
+	logger.Load().(loggerBox).Printf(format, v...)
+}
@@ -0,0 +1,25 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestSetLogger(t *testing.T) {
+	defer SetLogger(log.Default())
+	buf := bytes.NewBuffer(nil)
+	SetLogger(log.New(buf, "", 0))
+	Logf("hello %d", 1)
+	if buf.String() != "hello 1\n" {
+		t.Errorf("got '%s'", buf.String())
+	}
+	SetLogger(nil)
+	Logf("discarded")
+	if buf.String() != "hello 1\n" {
+		t.Errorf("got '%s' after SetLogger(nil)", buf.String())
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-air/gini/z"
+)
+
+const manifestName = "manifest.json"
+
+// ManifestVersion is the version of the manifest format written by Output.
+const ManifestVersion = 1
+
+// Manifest lists the contents of an output directory, so that missing or
+// corrupted files can be detected.  It is stored as json in the file
+// "manifest.json" of the directory.
+type Manifest struct {
+	Version   int              // ManifestVersion when written.
+	Aiger     string           // the path of the aiger.
+	AigerHash string           // sha256 of the aiger, in hex.
+	Results   []ManifestResult // the stored results.
+}
+
+// ManifestResult lists the files of a stored result.
+type ManifestResult struct {
+	M     z.Lit          // the bad state literal.
+	Files []ManifestFile // the result json, then any trace, invariant, frames or certificate.
+}
+
+// ManifestFile describes a file in an output directory.
+type ManifestFile struct {
+	Name   string // relative to the output directory.
+	Size   int64
+	Sha256 string // in hex.
+}
+
+// Manifest reads the manifest of `o`.  If `o` has no manifest, as for
+// output directories written by earlier versions of reach, then Manifest
+// returns nil and a nil error.
+func (o *Output) Manifest() (*Manifest, error) {
+	bs, err := ioutil.ReadFile(filepath.Join(o.root, manifestName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	man := &Manifest{}
+	if err := json.Unmarshal(bs, man); err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrOutputCorrupt, manifestName, err)
+	}
+	if man.Version < 1 || man.Version > ManifestVersion {
+		return nil, fmt.Errorf("%w: unsupported manifest version %d", ErrOutputCorrupt, man.Version)
+	}
+	return man, nil
+}
+
+// Validate checks the files of `o` against its manifest, returning an error
+// wrapping ErrOutputCorrupt for each missing or corrupted file and if the
+// aiger has changed.  Output directories without a manifest are not
+// checked.
+func (o *Output) Validate() []error {
+	man, err := o.Manifest()
+	if err != nil {
+		return []error{err}
+	}
+	if man == nil {
+		return nil
+	}
+	var errs []error
+	h, err := o.AigerHash()
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%w: aiger: %s", ErrOutputCorrupt, err))
+	} else if h != man.AigerHash {
+		errs = append(errs, fmt.Errorf("%w: aiger %s has changed", ErrOutputCorrupt, man.Aiger))
+	}
+	for _, r := range man.Results {
+		for _, mf := range r.Files {
+			got, err := hashFile(filepath.Join(o.root, mf.Name))
+			switch {
+			case os.IsNotExist(err):
+				errs = append(errs, fmt.Errorf("%w: %s is missing", ErrOutputCorrupt, mf.Name))
+			case err != nil:
+				errs = append(errs, fmt.Errorf("%w: %s: %s", ErrOutputCorrupt, mf.Name, err))
+			case got.Size != mf.Size || got.Sha256 != mf.Sha256:
+				errs = append(errs, fmt.Errorf("%w: %s has a bad checksum", ErrOutputCorrupt, mf.Name))
+			}
+		}
+	}
+	return errs
+}
+
+// writeManifest writes the manifest of `o`, with the files recorded by
+// writeAtomic.  Files of stored results which were not written by `o` are
+// hashed.  writeManifest should be called with the directory locked.
+func (o *Output) writeManifest() error {
+	h, err := o.AigerHash()
+	if err != nil {
+		return err
+	}
+	aig, err := filepath.EvalSymlinks(o.AigerPath())
+	if err != nil {
+		return err
+	}
+	old, err := o.Manifest()
+	if err != nil {
+		return err
+	}
+	man := &Manifest{Version: ManifestVersion, Aiger: aig, AigerHash: h}
+	ms := make(map[z.Lit]bool, len(o.bads))
+	for _, bad := range o.bads {
+		ms[bad.M] = true
+		mr := ManifestResult{M: bad.M}
+		ps := append([]string{o.litPath(bad.M, badExt)}, o.evidencePaths(bad.M)...)
+		for _, p := range ps {
+			name := filepath.Base(p)
+			mf, ok := o.files[name]
+			if !ok {
+				mf, err = hashFile(p)
+				if os.IsNotExist(err) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				mf.Name = name
+			}
+			mr.Files = append(mr.Files, mf)
+		}
+		man.Results = append(man.Results, mr)
+	}
+	// keep results stored by others since `o` read the directory.
+	if old != nil {
+		for _, r := range old.Results {
+			if !ms[r.M] {
+				man.Results = append(man.Results, r)
+			}
+		}
+	}
+	sort.Slice(man.Results, func(i, j int) bool { return man.Results[i].M < man.Results[j].M })
+	return o.writeAtomic(filepath.Join(o.root, manifestName), func(w io.Writer) error {
+		bs, err := json.MarshalIndent(man, "", "\t")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bs)
+		return err
+	})
+}
+
+// readManifestFiles records the files listed in the manifest of `o`, if any,
+// as if written by `o`.
+func (o *Output) readManifestFiles(man *Manifest) {
+	o.files = make(map[string]ManifestFile)
+	if man == nil {
+		return
+	}
+	for _, r := range man.Results {
+		for _, mf := range r.Files {
+			o.files[mf.Name] = mf
+		}
+	}
+}
+
+// writeAtomic writes the file with path `p` with atomicWrite and records its
+// size and checksum for the manifest.
+func (o *Output) writeAtomic(p string, f func(w io.Writer) error) error {
+	mf, err := atomicWrite(p, f)
+	if err != nil {
+		return err
+	}
+	if o.files == nil {
+		o.files = make(map[string]ManifestFile)
+	}
+	o.files[mf.Name] = mf
+	return nil
+}
+
+// atomicWrite writes the file with path `p` by calling `f` on a temporary
+// file in the same directory, which is renamed to `p` if `f` succeeds.  So
+// `p` is either unchanged or completely written, even if the process is
+// killed.  The directory is flushed after the rename, so that the new file
+// also survives a system crash.
+func atomicWrite(p string, f func(w io.Writer) error) (ManifestFile, error) {
+	dir, name := filepath.Split(p)
+	tmp, err := ioutil.TempFile(dir, "."+name+".tmp")
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	cw := &countWriter{w: io.MultiWriter(tmp, h)}
+	if err := f(cw); err != nil {
+		tmp.Close()
+		return ManifestFile{}, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return ManifestFile{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return ManifestFile{}, err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return ManifestFile{}, err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return ManifestFile{}, err
+	}
+	if err := syncDir(filepath.Dir(p)); err != nil {
+		return ManifestFile{}, err
+	}
+	return ManifestFile{Name: name, Size: cw.n, Sha256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// removeFile removes the file with path `p`, if it exists.
+func (o *Output) removeFile(p string) error {
+	delete(o.files, filepath.Base(p))
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(bs []byte) (int, error) {
+	n, err := c.w.Write(bs)
+	c.n += int64(n)
+	return n, err
+}
+
+func hashFile(p string) (ManifestFile, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	return ManifestFile{Name: filepath.Base(p), Size: n, Sha256: hex.EncodeToString(h.Sum(nil))}, nil
+}
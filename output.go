@@ -6,14 +6,14 @@ package reach
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/go-air/gini"
-	"github.com/go-air/gini/dimacs"
 	"github.com/go-air/gini/inter"
 	"github.com/go-air/gini/logic"
 	"github.com/go-air/gini/logic/aiger"
@@ -21,18 +21,37 @@ import (
 )
 
 const (
-	aigName  = "aig"
-	traceExt = ".trace"
-	invExt   = "-inv.cnf"
-	badExt   = "-bad.json"
+	aigName = "aig"
+	invExt  = "-inv.cnf"
+	frmExt  = "-frames.cnf"
+	certExt = "-cert.aig"
+	badExt  = "-bad.json"
 )
 
 // Output encapsulates the output of the reach command
 // checking subcommands.
+//
+// Results with different indices may be verified concurrently.
 type Output struct {
 	root     string
 	bads     []*Result
 	deadline time.Time // for time limiting verification of results.
+	traceFmt TraceFormat
+	names    map[z.Var]string // aiger symbol names, for named trace formats.
+	workers  int              // sat solvers per invariant check.
+	engine   string           // the engine whose results get opts.
+	opts     string
+	archive  string  // the archive from which `o` was extracted, if any.
+	lits     []z.Lit // aiger literals by variable, for MakeOutputSys.
+
+	// resMu guards bads and files, so that results may be appended and
+	// stored concurrently.
+	resMu sync.Mutex
+	files map[string]ManifestFile // files written, for the manifest.
+
+	mu      sync.Mutex
+	aig     *aiger.T // cached by Aiger.
+	aigHash string   // cached by aigerHash.
 }
 
 // MakeOutput creates an output object backed by directory root
@@ -69,26 +88,251 @@ func MakeOutput(g, dir string) (*Output, error) {
 	return res, nil
 }
 
+// MergeOutput is like MakeOutput, except that if the output directory for
+// `g` already exists, then MergeOutput uses it, so that several checkers or
+// processes may contribute results for the same aiger.  The aiger linked in
+// an existing directory must be the same file as `g`, otherwise MergeOutput
+// returns an error wrapping ErrOutputMismatch.
+//
+// Results of `o` are combined with those already in the directory when
+// stored, as described in Store.
+func MergeOutput(g, dir string) (*Output, error) {
+	var err error
+	g, err = filepath.Abs(g)
+	if err != nil {
+		return nil, err
+	}
+	base := filepath.Base(g)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	root := filepath.Join(dir, base)
+	if e := os.MkdirAll(root, 0755); e != nil {
+		return nil, e
+	}
+	res := &Output{root: root}
+	err = os.Symlink(g, res.AigerPath())
+	if err == nil {
+		return res, nil
+	}
+	if !os.IsExist(err) {
+		return nil, err
+	}
+	gi, err := os.Stat(g)
+	if err != nil {
+		return nil, err
+	}
+	ai, err := os.Stat(res.AigerPath())
+	if err != nil {
+		return nil, err
+	}
+	if !os.SameFile(gi, ai) {
+		return nil, fmt.Errorf("%w: %s is not linked to %s", ErrOutputMismatch, root, g)
+	}
+	return res, nil
+}
+
+// MakeOutputSys is like MakeOutput, except that the problem is given by the
+// sequential circuit `s` with bad state literals `bads` rather than by an
+// aiger file, and the output directory is dir/name.  Instead of a symlink,
+// the directory contains a binary aiger copy of `s` as written by WriteAiger,
+// so that the output may be verified and reopened with OpenOutput.  All the
+// AND gates of `s` are written, also those outside the cone of the latches
+// and bad states, so that results may refer to any literal of `s` as it is
+// when MakeOutputSys is called.
+//
+// The aiger may number the variables of `s` differently.  Results appended
+// to the output are given in terms of `s` and translated to the aiger, so
+// that the bad state literals, traces and invariants of its results
+// refer to the aiger.  Lit gives the translation of a literal of `s`.
+func MakeOutputSys(s *logic.S, name, dir string, bads ...z.Lit) (*Output, error) {
+	root := filepath.Join(dir, name)
+	_, e := os.Stat(root)
+	if e == nil {
+		return nil, os.ErrExist
+	}
+	if e := os.MkdirAll(root, 0755); e != nil {
+		return nil, e
+	}
+	res := &Output{root: root}
+	_, err := atomicWrite(res.AigerPath(), func(w io.Writer) error {
+		return writeAiger(w, s, nil, bads, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+	g, err := res.Aiger()
+	if err != nil {
+		return nil, err
+	}
+	n := g.Sys().Len()
+	res.lits = sysLits(s, g.Sys())
+	if g.Sys().Len() != n {
+		return nil, fmt.Errorf("%w: gates of %s missing in aiger", ErrInternal, name)
+	}
+	for i, m := range bads {
+		if res.Lit(m) != g.Bad[i] {
+			return nil, fmt.Errorf("%w: bad state %s written as %s", ErrInternal, m, g.Bad[i])
+		}
+	}
+	return res, nil
+}
+
+// sysLits returns the literal of `d` for each variable of `s`, where `d` is
+// the circuit of an aiger written from `s` by WriteAiger.  If the variables
+// of `s` and `d` are the same, then sysLits returns nil.
+func sysLits(s, d *logic.S) []z.Lit {
+	N := s.Len()
+	lits := make([]z.Lit, N)
+	lits[s.T.Var()] = d.T
+	dins := sysInputs(d)
+	for i, m := range sysInputs(s) {
+		lits[m.Var()] = dins[i]
+	}
+	for i, m := range s.Latches {
+		lits[m.Var()] = d.Latches[i]
+	}
+	tr := func(m z.Lit) z.Lit {
+		if m.IsPos() {
+			return lits[m.Var()]
+		}
+		return lits[m.Var()].Not()
+	}
+	id := true
+	for i := 1; i < N; i++ {
+		m := z.Var(i).Pos()
+		if s.Type(m) == logic.SAnd {
+			a, b := s.Ins(m)
+			lits[i] = d.And(tr(a), tr(b))
+		}
+		id = id && lits[i] == m
+	}
+	if id {
+		return nil
+	}
+	return lits
+}
+
+// Lit returns the literal of the aiger of `o` which corresponds to the
+// literal `m` of the circuit given to MakeOutputSys.  For other outputs, Lit
+// returns `m`.
+func (o *Output) Lit(m z.Lit) z.Lit {
+	if o.lits == nil || m == z.LitNull {
+		return m
+	}
+	d := o.lits[m.Var()]
+	if !m.IsPos() {
+		d = d.Not()
+	}
+	return d
+}
+
+// sysResult returns `bad`, translated to the aiger of `o` if `o` was made by
+// MakeOutputSys.
+func (o *Output) sysResult(bad *Result) *Result {
+	if o.lits == nil {
+		return bad
+	}
+	res := *bad
+	res.M = o.Lit(bad.M)
+	if bad.Trace != nil {
+		res.Trace = bad.Trace.mapLits(o.Lit)
+	}
+	if bad.Invariant != nil {
+		res.Invariant = make(Invariant, len(bad.Invariant))
+		for i, m := range bad.Invariant {
+			res.Invariant[i] = o.Lit(m)
+		}
+	}
+	if bad.Frames != nil {
+		res.Frames = make(Invariant, len(bad.Frames))
+		for i, m := range bad.Frames {
+			res.Frames[i] = o.Lit(m)
+		}
+	}
+	return &res
+}
+
 // OpenOutput tries to open an output as created by MakeOutput.
+//
+// If the output has a manifest, then its results are those listed in the
+// manifest, and OpenOutput checks them with Validate, returning an error
+// wrapping ErrOutputCorrupt if any file is missing or corrupted.
+//
+// If `d` is an archive written by WriteArchive, then OpenOutput opens it
+// with OpenArchive.
 func OpenOutput(d string) (*Output, error) {
+	if IsArchive(d) {
+		return OpenArchive(d)
+	}
 	out := &Output{root: d}
 	if err := out.readResults(); err != nil {
 		return nil, err
 	}
+	if errs := out.Validate(); len(errs) != 0 {
+		if len(errs) == 1 {
+			return nil, errs[0]
+		}
+		return nil, fmt.Errorf("%w (and %d more problems)", errs[0], len(errs)-1)
+	}
 	return out, nil
 }
 
+// SetTraceFormat sets the format in which Store writes traces.  The default
+// is TraceBinary.  For the other formats, signals are named from the aiger
+// symbol table.
+func (o *Output) SetTraceFormat(f TraceFormat) {
+	o.traceFmt = f
+}
+
+// TraceFormat returns the format in which Store writes traces.
+func (o *Output) TraceFormat() TraceFormat {
+	return o.traceFmt
+}
+
 // Results returns the bad states for which `o` contains
 // result information.
 func (o *Output) Results() []*Result {
+	o.resMu.Lock()
+	defer o.resMu.Unlock()
 	return o.bads
 }
 
 func (o *Output) readResults() error {
-	fis, err := ioutil.ReadDir(o.root)
+	bads, err := o.diskResults()
 	if err != nil {
 		return err
 	}
+	o.bads = append(o.bads, bads...)
+	return nil
+}
+
+// diskResults reads the results stored in the output directory, and
+// records the files listed in its manifest.  Without a manifest, results
+// are found by listing the directory.
+func (o *Output) diskResults() ([]*Result, error) {
+	man, err := o.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	o.readManifestFiles(man)
+	if man != nil {
+		bads := make([]*Result, 0, len(man.Results))
+		for _, r := range man.Results {
+			bad, err := o.readResult(filepath.Base(o.litPath(r.M, badExt)))
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("%w: result %s is missing", ErrOutputCorrupt, r.M)
+			}
+			if err != nil {
+				return nil, err
+			}
+			bads = append(bads, bad)
+		}
+		return bads, nil
+	}
+	fis, err := ioutil.ReadDir(o.root)
+	if err != nil {
+		return nil, err
+	}
+	var bads []*Result
 	for _, fi := range fis {
 		if fi.IsDir() {
 			continue
@@ -96,36 +340,37 @@ func (o *Output) readResults() error {
 		if !strings.HasSuffix(fi.Name(), badExt) {
 			continue
 		}
-		if err := o.readResult(fi.Name()); err != nil {
-			return err
+		bad, err := o.readResult(fi.Name())
+		if err != nil {
+			return nil, err
 		}
+		bads = append(bads, bad)
 	}
-	return nil
+	return bads, nil
 }
 
-func (o *Output) readResult(fn string) error {
+func (o *Output) readResult(fn string) (*Result, error) {
 	p := filepath.Join(o.root, fn)
 	f, e := os.Open(p)
 	if e != nil {
-		return e
+		return nil, e
 	}
 	defer f.Close()
 	bs, err := ioutil.ReadAll(f)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	bad := &Result{}
 	if err := json.Unmarshal(bs, bad); err != nil {
-		return err
+		return nil, err
 	}
-	o.bads = append(o.bads, bad)
-	return nil
+	return bad, nil
 }
 
 // IsVerifiable returns whether or not the `i`th bad
 // states formula has either
-//   1. a trace and is reachable; or
-//   2. an invariant is unreachable
+//  1. a trace and is reachable; or
+//  2. an invariant or certificate and is unreachable
 //
 // IsVerifiable checks the existence of files by
 // os.Stat to accomplish this.
@@ -147,21 +392,103 @@ func (o *Output) IsVerifiable(i int) bool {
 		}
 		return true
 	}
+	if _, err := os.Stat(o.CertificatePath(i)); err == nil {
+		return true
+	}
 	_, err := os.Stat(o.InvariantPath(i))
 	return err == nil
 }
 
 // AppendResult lets a checker append bad state information
-// to the output.
+// to the output.  AppendResult may be called concurrently with
+// StoreResult and Store.
 func (o *Output) AppendResult(bads ...*Result) {
-	o.bads = append(o.bads, bads...)
+	o.resMu.Lock()
+	defer o.resMu.Unlock()
+	for _, bad := range bads {
+		o.bads = append(o.bads, o.sysResult(bad))
+	}
+}
+
+// StoreResult appends `bad` to the output and stores it immediately, as
+// with Store, so that it is not lost if the process stops before the other
+// results are known.  Checkers call StoreResult as soon as a bad state is
+// decided.  StoreResult is safe for concurrent use, and `bad` should not be
+// modified afterwards.
+func (o *Output) StoreResult(bad *Result) error {
+	if err := checkEvidence(bad); err != nil {
+		return err
+	}
+	return o.update(func() error {
+		o.bads = append(o.bads, o.sysResult(bad))
+		return o.store()
+	})
+}
+
+// SetOptions records `opts` as the options of the checker `engine`, which
+// are stored in results of `engine` which have no options.
+func (o *Output) SetOptions(engine, opts string) {
+	o.engine, o.opts = engine, opts
 }
 
 // Store attempts to store `o`, including any traces or
 // invariants found in it's bad states.  Store returns
 // a non-nil error if there is a problem doing this.
+//
+// Store keeps only the strongest result for each bad state, among those of
+// `o` and those already stored in the directory, for example by another
+// process using MergeOutput.  Solved results are stronger than unknown
+// ones, deeper unknown results are stronger than shallower ones, and solved
+// results with a trace or invariant are stronger than those without.  If
+// two results disagree on whether a bad state is reachable, then Store
+// returns an error wrapping ErrContradiction and stores nothing.
+//
+// Store locks the directory, so concurrent processes and goroutines may
+// store results in the same directory.  After Store, the results of `o` are the merged
+// results.
 func (o *Output) Store() error {
+	return o.update(o.store)
+}
+
+func (o *Output) store() error {
+	old, err := o.diskResults()
+	if err != nil {
+		return err
+	}
+	var bads []*Result
+	var evs, fresh []bool
+	idx := make(map[z.Lit]int, len(old)+len(o.bads))
+	for _, bad := range old {
+		idx[bad.M] = len(bads)
+		bads = append(bads, bad)
+		evs = append(evs, o.hasEvidence(bad.M))
+		fresh = append(fresh, false)
+	}
+	for _, bad := range o.bads {
+		if err := checkEvidence(bad); err != nil {
+			return err
+		}
+		ev := bad.Trace != nil || len(bad.Invariant) != 0
+		j, ok := idx[bad.M]
+		if !ok {
+			idx[bad.M] = len(bads)
+			bads = append(bads, bad)
+			evs = append(evs, ev)
+			fresh = append(fresh, true)
+			continue
+		}
+		if err := contradicts(bads[j], bad); err != nil {
+			return err
+		}
+		if stronger(bad, bads[j], ev, evs[j]) {
+			bads[j], evs[j], fresh[j] = bad, ev, true
+		}
+	}
+	o.bads = bads
 	for i := range o.bads {
+		if !fresh[i] {
+			continue
+		}
 		if err := o.storeResult(i); err != nil {
 			return err
 		}
@@ -169,91 +496,170 @@ func (o *Output) Store() error {
 	return nil
 }
 
-func (o *Output) storeResult(i int) error {
-	bad := o.bads[i]
-	badPath := o.ResultPath(i)
-	f, e := os.Create(badPath)
-	if e != nil {
-		return e
+// update runs `f` with the output directory locked and then writes the
+// manifest and, if `o` was opened from an archive, the archive.
+func (o *Output) update(f func() error) error {
+	o.resMu.Lock()
+	defer o.resMu.Unlock()
+	unlock, err := lockDir(o.root)
+	if err != nil {
+		return err
 	}
-	defer f.Close()
-	d, e := json.MarshalIndent(bad, "", "\t")
-	if e != nil {
-		return e
+	defer unlock()
+	man, err := o.Manifest()
+	if err != nil {
+		return err
 	}
-	if _, e := f.Write(d); e != nil {
-		return e
+	o.readManifestFiles(man)
+	if err := f(); err != nil {
+		return err
 	}
-	if bad.Trace != nil {
-		if !bad.IsSolved() || !bad.IsReachable() {
-			panic(fmt.Sprintf("bad bad: %s", bad))
-		}
-		p := o.TracePath(i)
-		f, e := os.Create(p)
-		if e != nil {
-			return e
-		}
-		defer f.Close()
-		if err := bad.Trace.Encode(f); err != nil {
-			return err
-		}
+	if err := o.writeManifest(); err != nil {
+		return err
 	}
-	if len(bad.Invariant) != 0 {
-		if !bad.IsSolved() || bad.IsReachable() {
-			panic(fmt.Sprintf("bad bad: %s", bad))
+	return o.storeArchive()
+}
+
+// hasEvidence returns whether a trace, invariant or certificate for bad
+// state `m` is stored.
+func (o *Output) hasEvidence(m z.Lit) bool {
+	for _, p := range o.evidencePaths(m) {
+		if _, err := os.Stat(p); err == nil {
+			return true
 		}
-		return o.writeInv(i)
 	}
+	return false
+}
+
+func (o *Output) evidencePaths(m z.Lit) []string {
+	ps := []string{o.litPath(m, invExt), o.litPath(m, frmExt), o.litPath(m, certExt)}
+	for j := range traceFormatExts {
+		ps = append(ps, o.litPath(m, TraceFormat(j).Ext()))
+	}
+	return ps
+}
 
+// checkEvidence returns an error wrapping ErrInternal if the trace,
+// invariant or frames of `bad` do not fit its status.
+func checkEvidence(bad *Result) error {
+	switch {
+	case bad.Trace != nil && !bad.IsReachable():
+		return fmt.Errorf("%w: trace for %s", ErrInternal, bad)
+	case len(bad.Invariant) != 0 && !bad.IsUnreachable():
+		return fmt.Errorf("%w: invariant for %s", ErrInternal, bad)
+	case len(bad.Frames) != 0 && bad.IsSolved():
+		return fmt.Errorf("%w: frames for %s", ErrInternal, bad)
+	}
 	return nil
 }
 
-func (o *Output) writeInv(i int) error {
+func (o *Output) storeResult(i int) error {
 	bad := o.bads[i]
-	p := o.InvariantPath(i)
-	f, e := os.Create(p)
-	if e != nil {
-		return e
-	}
-	defer f.Close()
-	nv, nc := dimacsHeader(bad)
-	if _, err := fmt.Fprintf(f, "p cnf %d %d\n", nv, nc); err != nil {
+	if err := o.fillMeta(bad); err != nil {
 		return err
 	}
-	var err error
-	for _, m := range bad.Invariant {
-		if m == 0 {
-			_, err = fmt.Fprint(f, "0\n")
-		} else {
-			_, err = fmt.Fprintf(f, "%s ", m)
+	// remove evidence of any result replaced by bad.
+	for _, p := range o.evidencePaths(bad.M) {
+		if err := o.removeFile(p); err != nil {
+			return err
 		}
+	}
+	if bad.Trace != nil {
+		if err := o.writeTrace(i, o.traceFmt); err != nil {
+			return err
+		}
+	}
+	if len(bad.Invariant) != 0 {
+		if err := o.writeInv(i); err != nil {
+			return err
+		}
+	}
+	if len(bad.Frames) != 0 {
+		if err := o.writeAtomic(o.FramesPath(i), bad.Frames.WriteDimacs); err != nil {
+			return err
+		}
+	}
+	// the result is written last, so that its artifacts are present.
+	return o.writeResult(i)
+}
+
+func (o *Output) writeResult(i int) error {
+	return o.writeAtomic(o.ResultPath(i), func(w io.Writer) error {
+		d, err := json.MarshalIndent(o.bads[i], "", "\t")
 		if err != nil {
 			return err
 		}
+		_, err = w.Write(d)
+		return err
+	})
+}
+
+// fillMeta records information about the stored files of `bad` in `bad`.
+func (o *Output) fillMeta(bad *Result) error {
+	h, err := o.AigerHash()
+	if err != nil {
+		return err
+	}
+	bad.Version = Version
+	bad.AigerHash = h
+	if bad.Options == "" && bad.Engine == o.engine {
+		bad.Options = o.opts
+	}
+	if bad.Trace != nil {
+		bad.TraceLen = bad.Trace.Len()
+	}
+	if len(bad.Invariant) != 0 {
+		bad.InvClauses = bad.Invariant.Len()
+	}
+	if len(bad.Frames) != 0 {
+		bad.FrameClauses = bad.Frames.Len()
 	}
 	return nil
 }
 
-func dimacsHeader(bad *Result) (int, int) {
-	nv := 0
-	nc := 0
-	for _, m := range bad.Invariant {
-		if m == 0 {
-			nc++
-			continue
-		}
-		mv := int(m.Var())
-		if mv > nv {
-			nv = mv
+// AigerHash returns the sha256 hash of the aiger, in hex.
+func (o *Output) AigerHash() (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.aigHash != "" {
+		return o.aigHash, nil
+	}
+	mf, err := hashFile(o.AigerPath())
+	if err != nil {
+		return "", err
+	}
+	o.aigHash = mf.Sha256
+	return o.aigHash, nil
+}
+
+func (o *Output) writeTrace(i int, tf TraceFormat) error {
+	if tf != TraceBinary && o.names == nil {
+		g, err := o.Aiger()
+		if err != nil {
+			return err
 		}
+		o.names = AigerNames(g)
 	}
-	return nv, nc
+	return o.writeAtomic(o.tracePath(i, tf), func(w io.Writer) error {
+		return o.bads[i].Trace.EncodeFormat(w, tf, o.names)
+	})
+}
+
+func (o *Output) writeInv(i int) error {
+	return o.writeAtomic(o.InvariantPath(i), o.bads[i].Invariant.WriteDimacs)
 }
 
 // Remove tries to remove the output info, which is a recursive
 // directory removal.  It returns non-nil error if this directory
 // removal fails.  Once remove has been called, Store will fail.
+//
+// For outputs opened from an archive, the archive is removed as well.
 func (o *Output) Remove() error {
+	if o.archive != "" {
+		if err := os.Remove(o.archive); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
 	return os.RemoveAll(o.root)
 }
 
@@ -280,28 +686,52 @@ func (o *Output) TryVerify(dur time.Duration) []error {
 	return res
 }
 
-// TryVerifyResult
+// TryVerifyResult verifies the result at index i, as VerifyResult, within
+// `dur`.  Unlike VerifyResult, TryVerifyResult may be called concurrently for
+// different indices.
 func (o *Output) TryVerifyResult(i int, dur time.Duration) []error {
-	o.deadline = time.Now().Add(dur)
-	return o.VerifyResult(i)
+	return o.verifyResult(i, time.Now().Add(dur))
+}
+
+// SetCheckWorkers sets the number of sat solvers which may be used
+// concurrently to check the consecution of each invariant during
+// verification.  The default is 1.
+func (o *Output) SetCheckWorkers(j int) {
+	o.workers = j
 }
 
 // VerifyResult verifies the results associated with Result
 // at index i in the backing Result slice.
 //
 // It should only be called when the corresponding bad
-// has either an invariant or trace associated with it.
+// has either an invariant, certificate or trace associated with it.
+// If there is both an invariant and a certificate, both are verified.
 func (o *Output) VerifyResult(i int) []error {
+	return o.verifyResult(i, o.deadline)
+}
+
+func (o *Output) verifyResult(i int, deadline time.Time) []error {
 	_, terr := os.Stat(o.TracePath(i))
 	_, ierr := os.Stat(o.InvariantPath(i))
-	if os.IsNotExist(terr) && os.IsNotExist(ierr) {
+	_, cerr := os.Stat(o.CertificatePath(i))
+	if os.IsNotExist(terr) && os.IsNotExist(ierr) && os.IsNotExist(cerr) {
 		return []error{fmt.Errorf("nothing to verify")}
 	}
 	if os.IsNotExist(terr) {
-		if ierr != nil {
-			return []error{ierr}
+		var errs []error
+		if !os.IsNotExist(ierr) {
+			if ierr != nil {
+				return []error{ierr}
+			}
+			errs = o.verifyInv(i, deadline)
 		}
-		return o.verifyInv(i)
+		if !os.IsNotExist(cerr) {
+			if cerr != nil {
+				return append(errs, cerr)
+			}
+			errs = append(errs, o.verifyCert(i, deadline)...)
+		}
+		return errs
 	}
 	if terr != nil {
 		return []error{terr}
@@ -309,126 +739,215 @@ func (o *Output) VerifyResult(i int) []error {
 	return o.verifyTrace(i)
 }
 
-type dimacsVis struct {
-	sat *gini.Gini
-	ms  []z.Lit
+func (o *Output) readInv(i int) error {
+	var inv Invariant
+	if err := o.Invariant(&inv, i); err != nil {
+		return err
+	}
+	o.bads[i].Invariant = inv
+	return nil
 }
 
-func (d *dimacsVis) Add(m z.Lit) {
-	d.sat.Add(m)
-	d.ms = append(d.ms, m)
+func (o *Output) verifyInv(i int, deadline time.Time) []error {
+	s, err := o.sys()
+	if err != nil {
+		return []error{err}
+	}
+	if err := o.readInv(i); err != nil {
+		return []error{err}
+	}
+	bad := o.bads[i]
+	return failureErrors(bad.Invariant.CheckParallel(s, bad.M, time.Until(deadline), o.workers))
 }
-func (d *dimacsVis) Init(v, c int) {
+
+func (o *Output) verifyCert(i int, deadline time.Time) []error {
+	s, err := o.sys()
+	if err != nil {
+		return []error{err}
+	}
+	cert, err := o.Certificate(i)
+	if err != nil {
+		return []error{err}
+	}
+	return CheckAigerCertificate(s, o.bads[i].M, cert, time.Until(deadline))
 }
-func (d *dimacsVis) Eof() {
+
+// StoreCertificate writes the invariant of bad state i as an aiger
+// certificate, as described in AigerCertificate, to CertificatePath(i).
+// If the invariant is not in memory, it is read from InvariantPath(i).
+func (o *Output) StoreCertificate(i int) error {
+	return o.update(func() error { return o.storeCert(i) })
 }
-func (o *Output) readInv(i int, g *gini.Gini) error {
-	f, e := os.Open(o.InvariantPath(i))
-	if e != nil {
-		return e
+
+func (o *Output) storeCert(i int) error {
+	bad := o.bads[i]
+	if !bad.IsSolved() || bad.IsReachable() {
+		return fmt.Errorf("%s has no invariant", bad)
 	}
-	defer f.Close()
-	vis := &dimacsVis{sat: g}
-	if err := dimacs.ReadCnf(f, vis); err != nil {
+	if len(bad.Invariant) == 0 {
+		if err := o.readInv(i); err != nil {
+			return err
+		}
+	}
+	s, err := o.sys()
+	if err != nil {
 		return err
 	}
-	o.bads[i].Invariant = vis.ms
-	return nil
+	return o.writeAtomic(o.CertificatePath(i), func(w io.Writer) error {
+		return WriteAigerCertificate(w, s, bad.M, bad.Invariant)
+	})
 }
 
-func (o *Output) verifyInv(i int) []error {
-	aig, err := o.Aiger()
+// MinimizeInvariant replaces the invariant of bad state i by a smaller one,
+// as computed by the function MinimizeInvariant within `dur`, returning the
+// sizes of the invariant before and after.
+//
+// The smaller invariant is written to InvariantPath(i), together with a
+// certificate if there is one at CertificatePath(i), and then verified with
+// VerifyResult.  If verification fails, the original files are restored and
+// the verification errors are returned.  If minimization runs out of time,
+// the partially minimized invariant is kept as above, and the returned
+// errors consist of a single error wrapping ErrTimeout.
+func (o *Output) MinimizeInvariant(i int, dur time.Duration) (before, after InvariantSize, errs []error) {
+	err := o.update(func() error {
+		before, after, errs = o.minimizeInvariant(i, dur)
+		return nil
+	})
 	if err != nil {
-		return []error{err}
+		errs = append(errs, err)
 	}
-	sat := gini.New()
-	trans := aig.Sys()
-	var errors = o.verifyInvInit(trans, sat, o.bads[i])
-	cms := make([]z.Lit, 0, 16)
-	pri := NewPrimer(aig.Sys(), o.bads[i].M)
-	trans.ToCnf(sat)
-	// add invariant constraint
-	if err := o.readInv(i, sat); err != nil {
-		return []error{err}
+	return before, after, errs
+}
+
+func (o *Output) minimizeInvariant(i int, dur time.Duration) (before, after InvariantSize, errs []error) {
+	bad := o.bads[i]
+	if !bad.IsSolved() || bad.IsReachable() {
+		return before, after, []error{fmt.Errorf("%s has no invariant", bad)}
 	}
-	inv := o.bads[i].Invariant
-	for _, m := range inv {
-		sat.Add(m)
+	if err := o.readInv(i); err != nil {
+		return before, after, []error{err}
 	}
-	// check inv -> not(bad) as inv and bad is unsat.
-	sat.Assume(o.bads[i].M)
-	switch sat.Try(time.Until(o.deadline)) {
-	case 0:
-		errors = append(errors, fmt.Errorf("ErrTimeout"))
-		return errors
-	case 1:
-		errors = append(errors,
-			fmt.Errorf("ErrInvImpliesNotBad: %s\n", o.bads[i].M))
-
+	s, err := o.sys()
+	if err != nil {
+		return before, after, []error{err}
 	}
-	// consecution
-	for _, m := range inv {
-		if m == 0 {
-			res := sat.Try(time.Until(o.deadline))
-			switch res {
-			case -1:
-			case 0:
-				errors = append(errors, fmt.Errorf("ErrTimeout"))
-			case 1:
-				errors = append(errors,
-					fmt.Errorf("ErrNonConsecutiveInvariant: %v", cms))
+	org := bad.Invariant
+	before = org.Size()
+	min, err := minimizeInvariant(s, bad.M, org, dur)
+	if min == nil {
+		return before, before, []error{err}
+	}
+	_, cerr := os.Stat(o.CertificatePath(i))
+	hasCert := cerr == nil
+	store := func(inv Invariant) error {
+		bad.Invariant = inv
+		bad.InvClauses = inv.Len()
+		if err := o.writeInv(i); err != nil {
+			return err
+		}
+		if hasCert {
+			if err := o.storeCert(i); err != nil {
+				return err
 			}
-			cms = cms[:0]
-			continue
 		}
-		cms = append(cms, m)
-		mp := pri.Prime(m)
-		sat.Assume(mp.Not())
+		return o.writeResult(i)
+	}
+	if err := store(min); err != nil {
+		errs = append(errs, err)
+	} else {
+		errs = o.verifyResult(i, time.Now().Add(dur))
+	}
+	if len(errs) != 0 {
+		if err := store(org); err != nil {
+			errs = append(errs, err)
+		}
+		return before, before, errs
 	}
-	return errors
+	if err != nil {
+		return before, min.Size(), []error{fmt.Errorf("invariant partially minimized: %w", err)}
+	}
+	return before, min.Size(), nil
 }
 
-func (o *Output) verifyInvInit(trans *logic.S, sat *gini.Gini, bad *Result) []error {
-	inv := bad.Invariant
-	// initial condition
-	maxV := z.Var(0)
-	for _, m := range trans.Latches {
-		switch trans.Init(m) {
-		case trans.T:
-			sat.Assume(m)
-		case trans.F:
-			sat.Assume(m.Not())
-		}
-		if m.Var() > maxV {
-			maxV = m.Var()
-		}
+// minimizeInvariant is MinimizeInvariant, replaced in tests.
+var minimizeInvariant = MinimizeInvariant
+
+// MinimizeTrace replaces the trace of bad state i by a smaller one, as
+// computed by the function MinimizeTrace within `dur`, returning the sizes
+// of the trace before and after.
+//
+// The smaller trace is written to TracePath(i), in the format of the
+// original trace, and the depth of the result is set to its length.  The
+// trace is then verified with VerifyResult.  If verification fails, the
+// original trace is restored and the verification errors are returned.  If
+// minimization runs out of time, the partially minimized trace is kept as
+// above, and the returned errors consist of a single error wrapping
+// ErrTimeout.
+func (o *Output) MinimizeTrace(i int, dur time.Duration) (before, after TraceSize, errs []error) {
+	err := o.update(func() error {
+		before, after, errs = o.minimizeTrace(i, dur)
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
 	}
-	sat.Test(nil)
-	defer sat.Untest()
-	var res []error
-	cs := make([]z.Lit, 0, 13)
-	for _, m := range inv {
-		if m == 0 {
-			st := sat.Try(time.Until(o.deadline))
-			switch st {
-			case 0:
-				res = append(res, fmt.Errorf("ErrTimeout"))
-				return res
-			case 1:
-				res = append(res, fmt.Errorf("ErrInvariantInit[%d]: %v", bad.M, cs))
-			}
-			cs = cs[:0]
-			continue
+	return before, after, errs
+}
+
+func (o *Output) minimizeTrace(i int, dur time.Duration) (before, after TraceSize, errs []error) {
+	bad := o.bads[i]
+	if !bad.IsReachable() {
+		return before, after, []error{fmt.Errorf("%s has no trace", bad)}
+	}
+	_, tf := o.findTrace(i)
+	org, err := o.Trace(i)
+	if err != nil {
+		return before, after, []error{err}
+	}
+	s, err := o.sys()
+	if err != nil {
+		return before, after, []error{err}
+	}
+	before = org.Size()
+	min, err := MinimizeTrace(s, org, dur)
+	if min == nil {
+		return before, before, []error{err}
+	}
+	depth := bad.Depth
+	store := func(tr *Trace, depth int) error {
+		bad.Trace = tr
+		bad.TraceLen = tr.Len()
+		bad.Depth = depth
+		if err := o.writeTrace(i, tf); err != nil {
+			return err
 		}
-		if m.Var() > maxV {
-			continue
+		return o.writeResult(i)
+	}
+	if err := store(min, min.Len()); err != nil {
+		errs = append(errs, err)
+	} else {
+		errs = o.verifyResult(i, time.Now().Add(dur))
+	}
+	if len(errs) != 0 {
+		if err := store(org, depth); err != nil {
+			errs = append(errs, err)
 		}
-		sat.Assume(m.Not())
+		return before, before, errs
+	}
+	if err != nil {
+		return before, min.Size(), []error{fmt.Errorf("trace partially minimized: %w", err)}
 	}
-	if len(cs) != 0 {
-		res = append(res, fmt.Errorf("ErrInvNotNullTerminated[%d]", bad.M))
+	return before, min.Size(), nil
+}
+
+// Certificate reads the aiger certificate associated with bad state i.
+func (o *Output) Certificate(i int) (*aiger.T, error) {
+	f, err := os.Open(o.CertificatePath(i))
+	if err != nil {
+		return nil, err
 	}
-	return res
+	defer f.Close()
+	return aiger.ReadBinary(f)
 }
 
 func (o *Output) verifyTrace(i int) []error {
@@ -436,22 +955,23 @@ func (o *Output) verifyTrace(i int) []error {
 	if err != nil {
 		return []error{err}
 	}
-	g, err := o.Aiger()
+	s, err := o.sys()
 	if err != nil {
 		return []error{err}
 	}
-	return tr.Verify(g.Sys())
+	return tr.Verify(s)
 }
 
 // Trace tries to parse and return the trace associated with `i`th
 // bad state info.  Trace does not cache.
 func (o *Output) Trace(i int) (*Trace, error) {
-	f, err := os.Open(o.TracePath(i))
+	p, tf := o.findTrace(i)
+	f, err := os.Open(p)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
-	tr, err := DecodeTrace(f)
+	tr, err := DecodeTraceFormat(f, tf)
 	if err != nil {
 		return nil, err
 	}
@@ -466,11 +986,48 @@ func (o *Output) Invariant(dst inter.Adder, i int) error {
 		return e
 	}
 	defer f.Close()
+	inv, err := ReadDimacsInvariant(f)
+	if err != nil {
+		return err
+	}
+	for _, m := range inv {
+		dst.Add(m)
+	}
 	return nil
 }
 
-// Aiger tries to return the aiger for this problem
+// Aiger tries to return the aiger for this problem.
+//
+// The aiger is read once and then cached, so the result is shared by all
+// callers.  Functions which add nodes to the circuit, such as
+// Invariant.Check, should be given a copy of its Sys() if they may run
+// concurrently with others using the aiger.
 func (o *Output) Aiger() (*aiger.T, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.aig != nil {
+		return o.aig, nil
+	}
+	g, err := o.readAiger()
+	if err != nil {
+		return nil, err
+	}
+	o.aig = g
+	return g, nil
+}
+
+// sys returns a private copy of the circuit of the aiger for this problem.
+func (o *Output) sys() (*logic.S, error) {
+	g, err := o.Aiger()
+	if err != nil {
+		return nil, err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return g.Sys().Copy(), nil
+}
+
+func (o *Output) readAiger() (*aiger.T, error) {
 	p := o.AigerPath()
 	p, e := filepath.EvalSymlinks(p)
 	if e != nil {
@@ -499,20 +1056,57 @@ func (o *Output) AigerPath() string {
 }
 
 // TracePath gives the path to trace associated with bad state i.
+//
+// If there is a trace for bad state i stored in a format other than
+// o.TraceFormat(), then TracePath gives the path to that trace.
 func (o *Output) TracePath(i int) string {
-	return filepath.Join(o.root,
-		fmt.Sprintf("%d%s", o.bads[i].M, traceExt))
+	p, _ := o.findTrace(i)
+	return p
+}
+
+func (o *Output) findTrace(i int) (string, TraceFormat) {
+	p := o.tracePath(i, o.traceFmt)
+	if _, err := os.Stat(p); err == nil {
+		return p, o.traceFmt
+	}
+	for j := range traceFormatExts {
+		tf := TraceFormat(j)
+		q := o.tracePath(i, tf)
+		if _, err := os.Stat(q); err == nil {
+			return q, tf
+		}
+	}
+	return p, o.traceFmt
+}
+
+func (o *Output) tracePath(i int, tf TraceFormat) string {
+	return o.litPath(o.bads[i].M, tf.Ext())
 }
 
 // InvariantPath gives the path to the invariant associated with bad state i.
 func (o *Output) InvariantPath(i int) string {
-	return filepath.Join(o.root,
-		fmt.Sprintf("%d%s", o.bads[i].M, invExt))
+	return o.litPath(o.bads[i].M, invExt)
+}
+
+// FramesPath gives the path to the frames stored with the unknown result of
+// bad state i, such as those of an interrupted iic run.  The frames are not
+// an invariant and are not verified.
+func (o *Output) FramesPath(i int) string {
+	return o.litPath(o.bads[i].M, frmExt)
+}
+
+// CertificatePath gives the path to the aiger certificate associated with
+// bad state i.
+func (o *Output) CertificatePath(i int) string {
+	return o.litPath(o.bads[i].M, certExt)
 }
 
 // ResultPath gives the path associated with storing Result meta-data,
 // in json and parseable by json.Unmarshall.
 func (o *Output) ResultPath(i int) string {
-	return filepath.Join(o.root,
-		fmt.Sprintf("%d%s", o.bads[i].M, badExt))
+	return o.litPath(o.bads[i].M, badExt)
+}
+
+func (o *Output) litPath(m z.Lit, ext string) string {
+	return filepath.Join(o.root, fmt.Sprintf("%d%s", m, ext))
 }
@@ -0,0 +1,460 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/z"
+)
+
+func TestMergeOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, _, b, _, bad := genSafe()
+	fn := filepath.Join(dir, "safe.aig")
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteAiger(f, s, nil, []z.Lit{bad}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	store := func(r *Result) error {
+		out, err := MergeOutput(fn, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.AppendResult(r)
+		return out.Store()
+	}
+	if err := store(&Result{M: bad, Depth: 3, Engine: "bmc"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store(&Result{M: bad, Depth: 2, Engine: "sim"}); err != nil {
+		t.Fatal(err)
+	}
+	r := &Result{M: bad, Depth: 1, Engine: "iic", Invariant: Invariant{b.Not(), 0}}
+	r.SetUnreachable()
+	if err := store(r); err != nil {
+		t.Fatal(err)
+	}
+	err = store(&Result{M: bad, Status: 1, Engine: "bmc"})
+	if !errors.Is(err, ErrContradiction) {
+		t.Errorf("expected %s, got %v", ErrContradiction, err)
+	}
+	out, err := OpenOutput(filepath.Join(dir, "safe"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := out.Results()
+	if len(rs) != 1 || !rs[0].IsUnreachable() || rs[0].Engine != "iic" {
+		t.Fatalf("expected unreachable result from iic, got %v", rs)
+	}
+	if rs[0].InvClauses != 1 || len(rs[0].AigerHash) != 64 || rs[0].Version != Version {
+		t.Errorf("missing metadata: %+v", rs[0])
+	}
+	if errs := out.TryVerifyResult(0, time.Second); len(errs) != 0 {
+		t.Error(errs)
+	}
+	other := filepath.Join(dir, "other", "safe.aig")
+	os.Mkdir(filepath.Dir(other), 0755)
+	if err := ioutil.WriteFile(other, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MergeOutput(other, dir); !errors.Is(err, ErrOutputMismatch) {
+		t.Errorf("expected %s, got %v", ErrOutputMismatch, err)
+	}
+}
+
+func TestOutputManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, _, b, _, bad := genSafe()
+	fn := filepath.Join(dir, "safe.aig")
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteAiger(f, s, nil, []z.Lit{bad}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	out, err := MakeOutput(fn, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Result{M: bad, Invariant: Invariant{b.Not(), 0}}
+	r.SetUnreachable()
+	out.AppendResult(r)
+	if err := out.Store(); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.StoreCertificate(0); err != nil {
+		t.Fatal(err)
+	}
+	man, err := out.Manifest()
+	if err != nil || man == nil {
+		t.Fatalf("no manifest: %v", err)
+	}
+	if len(man.Results) != 1 || len(man.Results[0].Files) != 3 {
+		t.Fatalf("unexpected manifest %+v", man)
+	}
+	// a temporary file left by a killed process is ignored.
+	if err := ioutil.WriteFile(filepath.Join(out.RootDir(), ".x-bad.json.tmp1"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenOutput(out.RootDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(out.InvariantPath(0), []byte("p cnf 0 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenOutput(out.RootDir()); !errors.Is(err, ErrOutputCorrupt) {
+		t.Errorf("corrupt invariant: expected %s, got %v", ErrOutputCorrupt, err)
+	}
+	if err := os.Remove(out.CertificatePath(0)); err != nil {
+		t.Fatal(err)
+	}
+	if errs := out.Validate(); len(errs) != 2 {
+		t.Errorf("expected 2 validation errors, got %v", errs)
+	}
+}
+
+func TestStoreResultConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, bad := genRing(8, 3)
+	fn := filepath.Join(dir, "ring.aig")
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteAiger(f, s, nil, []z.Lit{bad}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	out, err := MakeOutput(fn, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, len(s.Latches))
+	for _, m := range s.Latches {
+		go func(m z.Lit) {
+			r := &Result{M: m, Depth: 2, Engine: "test"}
+			if m == s.Latches[0] {
+				r.Status = 1
+			}
+			errs <- out.StoreResult(r)
+		}(m)
+	}
+	for range s.Latches {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	o, err := OpenOutput(out.RootDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(o.Results()); n != len(s.Latches) {
+		t.Errorf("expected %d results, got %d", len(s.Latches), n)
+	}
+}
+
+func TestStoreResultEvidence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, bad := genRing(8, 3)
+	out, err := MakeOutputSys(s, "ring", dir, bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []*Result{
+		{M: bad, Trace: NewTrace(s, bad)},
+		{M: bad, Status: 1, Invariant: Invariant{bad.Not(), z.LitNull}},
+		{M: bad, Status: -1, Frames: Invariant{bad.Not(), z.LitNull}}} {
+		if err := out.StoreResult(r); !errors.Is(err, ErrInternal) {
+			t.Errorf("%s: got %v, expected ErrInternal", r, err)
+		}
+	}
+	o, err := OpenOutput(out.RootDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(o.Results()); n != 0 {
+		t.Errorf("stored %d results with bad evidence", n)
+	}
+	out.AppendResult(&Result{M: bad, Trace: NewTrace(s, bad)})
+	if err := out.Store(); !errors.Is(err, ErrInternal) {
+		t.Errorf("store got %v, expected ErrInternal", err)
+	}
+}
+
+func TestOutputArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, _, b, _, bad := genSafe()
+	fn := filepath.Join(dir, "safe.aig")
+	f, err := os.Create(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteAiger(f, s, nil, []z.Lit{bad}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	out, err := MakeOutput(fn, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Result{M: bad, Invariant: Invariant{b.Not(), 0}}
+	r.SetUnreachable()
+	out.AppendResult(r)
+	if err := out.Store(); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, "safe"+ArchiveExt)
+	f, err = os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := out.WriteArchive(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	// the archive does not depend on the aiger or the directory.
+	if err := out.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(fn); err != nil {
+		t.Fatal(err)
+	}
+	if !IsArchive(p) || IsArchive(dir) {
+		t.Fatalf("IsArchive")
+	}
+	a, err := OpenOutput(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := a.TryVerify(time.Second); len(errs) != 0 {
+		t.Error(errs)
+	}
+	if err := a.StoreCertificate(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(a.RootDir()); !os.IsNotExist(err) {
+		t.Errorf("archive directory not removed: %v", err)
+	}
+	a, err = OpenArchive(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if _, err := a.Certificate(0); err != nil {
+		t.Errorf("certificate not stored in archive: %s", err)
+	}
+	if err := ioutil.WriteFile(p, []byte{0x1f, 0x8b, 0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenOutput(p); !errors.Is(err, ErrOutputCorrupt) {
+		t.Errorf("expected %s, got %v", ErrOutputCorrupt, err)
+	}
+}
+
+func TestMakeOutputSys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// variables not in aiger order: a latch before the input.
+	s := logic.NewS()
+	a := s.Latch(s.F)
+	in := s.Lit()
+	b, c := s.Latch(s.F), s.Latch(s.F)
+	s.SetNext(a, in)
+	s.SetNext(b, s.And(a, in))
+	s.SetNext(c, c)
+	bad := s.And(b, a)
+	tr := NewTrace(s, bad)
+	vs := make([]bool, s.Len())
+	for _, st := range [][3]bool{{false, false, true}, {true, false, true}, {true, true, false}} {
+		vs[a.Var()], vs[b.Var()], vs[in.Var()] = st[0], st[1], st[2]
+		s.Eval(vs)
+		tr.Append(vs)
+	}
+	if errs := tr.Verify(s); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	out, err := MakeOutputSys(s, "sys", dir, bad, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Lit(a) == a {
+		t.Errorf("expected renumbered latch")
+	}
+	r0 := &Result{M: bad, Depth: 2}
+	r0.SetReachable(tr)
+	r1 := &Result{M: c, Invariant: Invariant{c.Not(), 0}}
+	r1.SetUnreachable()
+	out.AppendResult(r0, r1)
+	if err := out.Store(); err != nil {
+		t.Fatal(err)
+	}
+	if errs := out.TryVerify(time.Second); len(errs) != 0 {
+		t.Error(errs)
+	}
+	o, err := OpenOutput(out.RootDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := o.Results()
+	if len(rs) != 2 || rs[0].M != out.Lit(c) || rs[1].M != out.Lit(bad) {
+		t.Fatalf("unexpected results %v", rs)
+	}
+	if errs := o.TryVerify(time.Second); len(errs) != 0 {
+		t.Error(errs)
+	}
+
+	// an invariant over a gate outside the cone of the latches and bad
+	// states.
+	s = logic.NewS()
+	a, b = s.Latch(s.F), s.Latch(s.F)
+	s.SetNext(a, a)
+	s.SetNext(b, b)
+	or := s.Or(a, b)
+	out, err = MakeOutputSys(s, "cone", dir, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Result{M: a, Invariant: Invariant{or.Not(), 0}}
+	r.SetUnreachable()
+	out.AppendResult(r)
+	if err := out.Store(); err != nil {
+		t.Fatal(err)
+	}
+	o, err = OpenOutput(out.RootDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := o.TryVerify(time.Second); len(errs) != 0 {
+		t.Error(errs)
+	}
+}
+
+func TestOutputMinimizeInvariantTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, a, b, _, bad := genSafe()
+	out, err := MakeOutputSys(s, "safe", dir, bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Result{M: bad, Invariant: Invariant{a.Not(), b.Not(), 0, b.Not(), 0}}
+	r.SetUnreachable()
+	out.AppendResult(r)
+	if err := out.Store(); err != nil {
+		t.Fatal(err)
+	}
+	defer func(f func(*logic.S, z.Lit, Invariant, time.Duration) (Invariant, error)) {
+		minimizeInvariant = f
+	}(minimizeInvariant)
+	minimizeInvariant = func(s *logic.S, bad z.Lit, inv Invariant, dur time.Duration) (Invariant, error) {
+		min, _ := MinimizeInvariant(s, bad, inv, dur)
+		return min, ErrTimeout
+	}
+	before, after, errs := out.MinimizeInvariant(0, time.Second)
+	if len(errs) != 1 || !errors.Is(errs[0], ErrTimeout) {
+		t.Errorf("expected %s, got %v", ErrTimeout, errs)
+	}
+	if before.Clauses != 2 || after.Clauses != 1 {
+		t.Errorf("minimized %s to %s", before, after)
+	}
+	o, err := OpenOutput(out.RootDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	if errs := o.TryVerify(time.Second); len(errs) != 0 {
+		t.Error(errs)
+	}
+	if n := o.Results()[0].InvClauses; n != 1 {
+		t.Errorf("stored invariant has %d clauses", n)
+	}
+}
+
+func TestOutputMinimizeTrace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, bad, tr := genCounterTrace(12)
+	out, err := MakeOutputSys(s, "counter", dir, bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Result{M: bad, Depth: tr.Len()}
+	r.SetReachable(tr)
+	out.AppendResult(r)
+	if err := out.Store(); err != nil {
+		t.Fatal(err)
+	}
+	o, err := OpenOutput(out.RootDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	before, after, errs := o.MinimizeTrace(0, time.Second)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if before.Len != 12 || after.Len != 8 {
+		t.Errorf("minimized %s to %s", before, after)
+	}
+	o, err = OpenOutput(out.RootDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	if errs := o.TryVerify(time.Second); len(errs) != 0 {
+		t.Error(errs)
+	}
+	if errs := o.Validate(); len(errs) != 0 {
+		t.Error(errs)
+	}
+	if r := o.Results()[0]; r.TraceLen != 8 || r.Depth != 8 {
+		t.Errorf("stored trace of length %d at depth %d", r.TraceLen, r.Depth)
+	}
+}
@@ -20,13 +20,25 @@ type Primer struct {
 //
 // NewPrimer may, and usually does, add nodes `t`.
 func NewPrimer(t *logic.S, ps ...z.Lit) *Primer {
+	return newPrimer(t, false, ps)
+}
+
+// newInputPrimer is like NewPrimer, but primes each input of `t` to a new
+// input of `t`, so that primed literals are over the next states and the
+// inputs of the next step.  This is needed to check invariants and bad
+// states which depend on inputs.
+func newInputPrimer(t *logic.S, ps ...z.Lit) *Primer {
+	return newPrimer(t, true, ps)
+}
+
+func newPrimer(t *logic.S, inputs bool, ps []z.Lit) *Primer {
 	primed := make([]z.Lit, t.Len())
 	res := &Primer{trans: t, primed: primed}
 	for _, m := range t.Latches {
-		primeRec(t, m, primed)
+		primeRec(t, m, primed, inputs)
 	}
 	for _, p := range ps {
-		primeRec(t, p, primed)
+		primeRec(t, p, primed, inputs)
 	}
 	return res
 }
@@ -44,7 +56,7 @@ func (p *Primer) Prime(m z.Lit) z.Lit {
 	return mvp.Not()
 }
 
-func primeRec(trans *logic.S, p z.Lit, primed []z.Lit) z.Lit {
+func primeRec(trans *logic.S, p z.Lit, primed []z.Lit, inputs bool) z.Lit {
 	pVar := p.Var()
 	prime := primed[pVar]
 	if prime != z.LitNull {
@@ -56,7 +68,7 @@ func primeRec(trans *logic.S, p z.Lit, primed []z.Lit) z.Lit {
 	switch trans.Type(p) {
 	case logic.SAnd:
 		a, b := trans.Ins(p)
-		a, b = primeRec(trans, a, primed), primeRec(trans, b, primed)
+		a, b = primeRec(trans, a, primed, inputs), primeRec(trans, b, primed, inputs)
 		res := trans.And(a, b)
 
 		primed[pVar] = res
@@ -65,6 +77,14 @@ func primeRec(trans *logic.S, p z.Lit, primed []z.Lit) z.Lit {
 		}
 		return res.Not()
 	case logic.SInput:
+		if inputs {
+			res := trans.Lit()
+			primed[pVar] = res
+			if p.IsPos() {
+				return res
+			}
+			return res.Not()
+		}
 		primed[pVar] = pVar.Pos()
 		return p
 	case logic.SConst:
@@ -16,8 +16,22 @@ type Result struct {
 	Status    int           // 1=reachable -1=unreachable 0=unknown
 	Depth     int           // The depth of the analysis (= length of trace or depth of unreachability)
 	Dur       time.Duration // If a timeout was specified, then its duration.
-	Trace     *Trace        `json:"-"` // A trace (optional even if Reachable is true)
-	Invariant []z.Lit       `json:"-"` // invariant in cnf.
+	Trace     *Trace        `json:"-"`          // A trace (optional even if Reachable is true)
+	Invariant Invariant     `json:"-"`          // invariant in cnf.
+	Frames    Invariant     `json:"-"`          // for unknown results, clauses holding in all states reachable within Depth steps.
+	Engine    string        `json:",omitempty"` // the checker which produced the result, such as "iic".
+	Options   string        `json:",omitempty"` // the options of the checker, if recorded.
+	Seed      int64         `json:",omitempty"` // the random seed of the checker, if any.
+
+	// Stats holds checker statistics, such as "SatCalls".
+	Stats map[string]int64 `json:",omitempty"`
+
+	// The following are filled in by Output.Store.
+	Version      string `json:",omitempty"` // the version of reach which stored the result.
+	AigerHash    string `json:",omitempty"` // sha256 of the aiger, in hex.
+	TraceLen     int    `json:",omitempty"` // the length of the trace, if any.
+	InvClauses   int    `json:",omitempty"` // the number of clauses of the invariant, if any.
+	FrameClauses int    `json:",omitempty"` // the number of clauses of the frames, if any.
 }
 
 func (b *Result) String() string {
@@ -72,5 +86,43 @@ func (b *Result) SetUnreachable() {
 // Add is for storing an invarant proving unreachability
 // in memory.  It is optional.
 func (b *Result) Add(m z.Lit) {
-	b.Invariant = append(b.Invariant, m)
+	b.Invariant.Add(m)
+}
+
+// stronger returns whether `a` is a stronger result than `b` for the same
+// bad state.  `aEv` and `bEv` give whether each result has evidence, a trace
+// or an invariant.  Solved results are stronger than unknown ones and
+// unknown results are stronger when they are deeper.  Among solved results,
+// those with evidence are stronger, then shorter traces.
+func stronger(a, b *Result, aEv, bEv bool) bool {
+	if a.IsSolved() != b.IsSolved() {
+		return a.IsSolved()
+	}
+	if !a.IsSolved() {
+		return a.Depth > b.Depth
+	}
+	if aEv != bEv {
+		return aEv
+	}
+	if a.IsReachable() {
+		return a.Depth < b.Depth
+	}
+	return false
+}
+
+// contradicts returns an error wrapping ErrContradiction if `a` and `b`
+// disagree about the reachability of their bad state.
+func contradicts(a, b *Result) error {
+	if !a.IsSolved() || !b.IsSolved() || a.Status == b.Status {
+		return nil
+	}
+	return fmt.Errorf("%w: bad %s is %s by %s and %s by %s", ErrContradiction,
+		a.M, a.FormatStatus(), a.engine(), b.FormatStatus(), b.engine())
+}
+
+func (b *Result) engine() string {
+	if b.Engine == "" {
+		return "unknown engine"
+	}
+	return b.Engine
 }
@@ -7,5 +7,8 @@
 // by doing 64 independent simulation steps per logic gate in one
 // 64 bit word bitwise operation.
 //
-// Interfaces are provided for watches and monitoring.
+// Interfaces are provided for watches and monitoring.  An Observer may be
+// supplied in Options to be called back on watches, steps and restarts, and
+// may pause or stop the simulation.  Options.EventChan provides the same
+// events over a channel.
 package sim
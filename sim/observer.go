@@ -0,0 +1,262 @@
+// Copyright (c) 2021 The Reach authors (see AUTHORS)
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package sim
+
+import (
+	"fmt"
+
+	"github.com/go-air/gini/z"
+	"github.com/go-air/reach"
+)
+
+// Action tells the simulator what to do after an Observer callback.
+type Action int
+
+const (
+	// Continue continues the simulation.
+	Continue Action = iota
+	// Pause suspends the simulation until T.Resume is called.
+	Pause
+	// Stop ends the simulation.
+	Stop
+)
+
+func (a Action) String() string {
+	switch a {
+	case Continue:
+		return "continue"
+	case Pause:
+		return "pause"
+	case Stop:
+		return "stop"
+	default:
+		panic("unreachable")
+	}
+}
+
+// Observer is an interface for monitoring a simulation.
+//
+// All callbacks are made on the goroutine running T.Simulate, so an Observer
+// may freely inspect `t` and any Lane during the callback.  Values and the
+// window are owned by the simulator and should be copied if they are needed
+// after the callback returns.
+type Observer interface {
+	// OnWatch is called when the watch `m` is true in lane `l`.
+	OnWatch(t *T, m z.Lit, l Lane) Action
+
+	// OnStep is called every Options.StepEvery steps, if
+	// Options.StepEvery is positive.
+	OnStep(t *T) Action
+
+	// OnRestart is called when the simulator restarts from the initial
+	// states for the `n`th time, n > 0.
+	OnRestart(t *T, n int) Action
+}
+
+// Lane gives access to one of the 64 simulations run in parallel by T.
+type Lane struct {
+	t *T
+	i uint
+}
+
+// Lane returns the lane with index i in [0..64).
+func (t *T) Lane(i int) Lane {
+	if i < 0 || i >= 64 {
+		panic(fmt.Sprintf("lane %d out of range", i))
+	}
+	return Lane{t: t, i: uint(i)}
+}
+
+// Index returns the index of `l` in [0..64).
+func (l Lane) Index() int {
+	return int(l.i)
+}
+
+// Value returns the value of `m` in lane `l` at the current step.
+func (l Lane) Value(m z.Lit) bool {
+	return l.WindowValue(0, m)
+}
+
+// WindowLen returns the number of steps available in the window, including
+// the current step.
+func (l Lane) WindowLen() int {
+	n := len(l.t.window)
+	if l.t.steps+1 < int64(n) {
+		return int(l.t.steps + 1)
+	}
+	return n
+}
+
+// WindowValue returns the value of `m` in lane `l` `back` steps before the
+// current step.  WindowValue panics if `back` is not less than
+// l.WindowLen().
+func (l Lane) WindowValue(back int, m z.Lit) bool {
+	if back < 0 || back >= l.WindowLen() {
+		panic(fmt.Sprintf("window step %d out of range", back))
+	}
+	n := len(l.t.window)
+	j := (l.t.wi - 1 - back + 2*n) % n
+	v := l.t.window[j][m.Var()]&(1<<l.i) != 0
+	if !m.IsPos() {
+		v = !v
+	}
+	return v
+}
+
+// Trace returns a trace of the window of `l` with watch `w`.
+func (l Lane) Trace(w z.Lit) *reach.Trace {
+	return l.t.genTrace(w, l.i)
+}
+
+// Steps returns the number of steps since the last (re)start.
+func (t *T) Steps() int64 {
+	return t.steps
+}
+
+// Values returns the current values of all nodes, 64 lanes per
+// uint64, indexed by variable.  The result is owned by `t`.
+func (t *T) Values() []uint64 {
+	return t.vsA
+}
+
+// Resume resumes a simulation paused by an Observer with action `a`, which
+// should be Continue or Stop.  Resume blocks until the simulation receives
+// `a` or T.Stop is called, and so should only be called after an Observer
+// callback returned Pause.
+func (t *T) Resume(a Action) {
+	select {
+	case t.resume <- a:
+	case <-t.stopc:
+	}
+}
+
+// observe returns whether to continue after an Observer callback returned
+// `a`, waiting for Resume or Stop if `a` is Pause.
+func (t *T) observe(a Action) bool {
+	if a == Pause {
+		select {
+		case a = <-t.resume:
+		case <-t.stopc:
+			return false
+		}
+	}
+	return a != Stop
+}
+
+// chanObserver adapts the Event channel protocol of Options.EventChan to the
+// Observer interface.  It does not modify the Options, so the channel and flags
+// may be read by other goroutines during the simulation.  Simulate sets
+// Options.EventChan to nil once the channel is closed.
+type chanObserver struct {
+	ch      chan *Event
+	flags   EventFlag
+	verbose bool
+	closed  bool
+}
+
+func newChanObserver(opts *Options) *chanObserver {
+	return &chanObserver{ch: opts.EventChan, flags: opts.EventFlags, verbose: opts.Verbose}
+}
+
+func (c *chanObserver) OnWatch(t *T, m z.Lit, l Lane) Action {
+	if c.closed {
+		return Continue
+	}
+	var tr *reach.Trace
+	for i, w := range t.watches {
+		if w == m {
+			tr = t.traces[i]
+			break
+		}
+	}
+	if c.verbose {
+		reach.Logf("[sim] executing watch event over channel.\n")
+		defer reach.Logf("[sim] done executing watch event.\n")
+	}
+	ev := &Event{}
+	c.fill(t, m, l.Index(), tr, ev)
+	if c.flags&FlagWait == 0 {
+		select {
+		case c.ch <- ev:
+		default:
+			return Continue
+		}
+	} else {
+		c.ch <- ev
+	}
+	if c.flags&FlagRoundTrip == 0 {
+		return Continue
+	}
+	ev, ok := <-c.ch
+	if !ok {
+		c.closed = true
+		return Stop
+	}
+	c.flags = ev.F
+	if c.flags&FlagStop != 0 {
+		c.close()
+		return Stop
+	}
+	return Continue
+}
+
+func (c *chanObserver) OnStep(t *T) Action {
+	return Continue
+}
+
+func (c *chanObserver) OnRestart(t *T, n int) Action {
+	return Continue
+}
+
+func (c *chanObserver) fill(t *T, m z.Lit, i int, tr *reach.Trace, ev *Event) {
+	flag := c.flags
+	ev.N = t.steps
+	ev.M = m
+	ev.I = i
+	ev.F = flag
+	ev.WI = t.wi
+	if flag&FlagCopyV != 0 {
+		ev.V = make([]uint64, t.trans.Len())
+		copy(ev.V, t.vsA)
+	} else {
+		ev.V = t.vsA
+	}
+	if flag&FlagCopyW != 0 {
+		ev.W = make([][]uint64, len(t.window))
+		for i := range ev.W {
+			ev.W[i] = make([]uint64, len(t.window[i]))
+			copy(ev.W[i], t.window[i])
+		}
+	} else {
+		ev.W = t.window
+	}
+	if flag&FlagTrace != 0 {
+		ev.T = tr
+	} else {
+		ev.T = nil
+	}
+}
+
+func (c *chanObserver) close() {
+	if !c.closed {
+		close(c.ch)
+		c.closed = true
+	}
+}
@@ -45,8 +45,16 @@ type Options struct {
 	Verbose bool
 	// Events, ignored if EventChan is nil
 	EventFlags EventFlag
-	// EventChan is a channel on which to communicate simulation events.
-	EventChan chan *Event
+	// EventChan is a channel on which to communicate simulation events,
+	// ignored if Observer is not nil.  Simulate closes EventChan and sets
+	// it to nil when it returns, so a new channel is needed for each call
+	// to Simulate.  It is not encoded in json, like Observer.
+	EventChan chan *Event `json:"-"`
+	// Observer, if not nil, is called back on simulation events.
+	Observer Observer `json:"-"`
+	// StepEvery tells the simulator to call Observer.OnStep every StepEvery
+	// steps, if positive.
+	StepEvery int64
 }
 
 // NewOptions gives default options.
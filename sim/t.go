@@ -6,6 +6,7 @@ package sim
 import (
 	"fmt"
 	"math/rand"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-air/reach"
@@ -28,9 +29,16 @@ type T struct {
 	window      [][]uint64
 	wi          int
 	steps       int64
+	ttlSteps    int64 // steps over all calls to Simulate
 	luby        *luby
+	resume      chan Action
 
-	opts *Options
+	opts  *Options
+	obs   Observer
+	out   *reach.Output
+	err   error         // first error storing results in out
+	stop  int32         // set by Stop
+	stopc chan struct{} // closed by Stop
 }
 
 // New creates a new simulator.
@@ -55,11 +63,14 @@ func New(trans *logic.S, bads ...z.Lit) *T {
 			res.inputs = append(res.inputs, m)
 		}
 	}
+	res.resume = make(chan Action)
+	res.stopc = make(chan struct{})
 	res.opts = NewOptions()
 	res.SetOptions(res.opts)
 	return res
 }
 
+// SetOptions sets the options for subsequent calls to Simulate.
 func (t *T) SetOptions(opts *Options) {
 	t.opts = opts
 	t.setWindow(opts.TraceWindow)
@@ -81,43 +92,74 @@ func (t *T) setWindow(n int) {
 }
 
 // Simulate runs the simulation with the current options.
+//
+// If Options.Observer is nil and Options.EventChan is not, then events are
+// sent over Options.EventChan, which is closed and set to nil when Simulate
+// returns.
 func (t *T) Simulate() int64 {
 	ticker := time.NewTicker(time.Second)
+	t.obs = t.opts.Observer
+	var co *chanObserver
+	if t.obs == nil && t.opts.EventChan != nil {
+		co = newChanObserver(t.opts)
+		t.obs = co
+	}
 	defer func() {
 		ticker.Stop()
-		if t.opts.EventChan != nil {
-			close(t.opts.EventChan)
+		if co != nil {
+			co.close()
 			t.opts.EventChan = nil
 		}
+		t.obs = nil
 	}()
 
 	ttl := int64(0)
 
 	for i := 0; i < t.opts.N; i++ {
+		if i > 0 && t.obs != nil {
+			if !t.observe(t.obs.OnRestart(t, i)) {
+				return ttl
+			}
+		}
 		if t.opts.RestartFactor != 0 {
 			t.opts.MaxDepth = int64(int(t.luby.Next()) * t.opts.RestartFactor)
 		}
-		ttl += t.simulateOne(ticker)
+		n, ok := t.simulateOne(ticker)
+		ttl += n
+		t.ttlSteps += n
+		if !ok {
+			return ttl
+		}
 	}
 	return ttl
 }
 
-// SimulateFor runs the simulation for at most
-// `dur` time.  Simulation may stop early as per
-// other configuration.
-func (t *T) simulateOne(ticker *time.Ticker) int64 {
+// Stop asks Simulate, running in another goroutine, to return after the
+// current step, as if an observer had stopped it.  The results found so far
+// are kept for FillOutput.  A simulation paused by an Observer is stopped
+// without waiting for Resume.  Stop may be called at any time, and once
+// called, subsequent calls to Simulate return after one step.
+func (t *T) Stop() {
+	if atomic.CompareAndSwapInt32(&t.stop, 0, 1) {
+		close(t.stopc)
+	}
+}
+
+// simulateOne runs one simulation from the initial states.  It returns the
+// number of steps and false if an observer or Stop stopped the simulation.
+func (t *T) simulateOne(ticker *time.Ticker) (int64, bool) {
 	t.deadLine = time.Now().Add(t.opts.Duration)
 	res := int64(0)
 	t.init()
 	trans := t.trans
 	if t.opts.Verbose {
-		fmt.Printf("[sim] initialized ... starting simulation.\n")
+		reach.Logf("[sim] initialized ... starting simulation.\n")
 	}
 	for {
 		if t.opts.Verbose {
 			select {
 			case <-ticker.C:
-				fmt.Printf("[sim] step %d\n", t.steps)
+				reach.Logf("[sim] step %d\n", t.steps)
 			default:
 			}
 		}
@@ -125,15 +167,21 @@ func (t *T) simulateOne(ticker *time.Ticker) int64 {
 		t.addStep(t.vsA)
 		if time.Until(t.deadLine) <= 0 {
 			if t.opts.Verbose {
-				fmt.Printf("[sim] deadline reached after %d steps.\n", t.steps)
+				reach.Logf("[sim] deadline reached after %d steps.\n", t.steps)
+			}
+			return res, true
+		}
+		if atomic.LoadInt32(&t.stop) != 0 {
+			if t.opts.Verbose {
+				reach.Logf("[sim] stopped after %d steps.\n", t.steps)
 			}
-			return res
+			return res, false
 		}
 		if t.steps >= t.opts.MaxDepth {
 			if t.opts.Verbose {
-				fmt.Printf("[sim] maxdepth %d reached.\n", t.steps)
+				reach.Logf("[sim] maxdepth %d reached.\n", t.steps)
 			}
-			return res
+			return res, true
 		}
 		if debugState {
 			fmt.Printf("latch states:\n")
@@ -168,7 +216,7 @@ func (t *T) simulateOne(ticker *time.Ticker) int64 {
 				continue
 			}
 			if debugState || t.opts.Verbose {
-				fmt.Printf("[sim] watch %d: %s has %b\n", i, m, wvs)
+				reach.Logf("[sim] watch %d: %s has %b\n", i, m, wvs)
 			}
 			ttl := 0
 			for s := uint(0); s < 64; s++ {
@@ -186,110 +234,73 @@ func (t *T) simulateOne(ticker *time.Ticker) int64 {
 				}
 				if t.traces[i] == nil {
 					t.traces[i] = t.genTrace(m, s)
+					t.storeResult(i)
+				}
+				if t.obs != nil && !t.observe(t.obs.OnWatch(t, m, Lane{t: t, i: s})) {
+					return res, false
 				}
-				t.execEvent(m, int(s), t.traces[i])
 			}
 			if min > ttl {
 				min = ttl
 			}
 		}
+		if t.obs != nil && t.opts.StepEvery > 0 && (res+1)%t.opts.StepEvery == 0 {
+			if !t.observe(t.obs.OnStep(t)) {
+				return res, false
+			}
+		}
 		t.vsA, t.vsB = t.vsB, t.vsA
 		res++
 		t.steps = res
 		if min >= t.opts.WatchUntil {
-			return res
+			return res, true
 		}
 	}
 }
 
-func (t *T) fillEvent(m z.Lit, i int, tr *reach.Trace, ev *Event) {
-	flag := t.opts.EventFlags
-	ev.N = t.steps
-	ev.M = m
-	ev.I = i
-	ev.F = flag
-	ev.WI = t.wi
-	if flag&FlagCopyV != 0 {
-		ev.V = make([]uint64, t.trans.Len())
-		copy(ev.V, t.vsA)
-	} else {
-		ev.V = t.vsA
-	}
-	if flag&FlagCopyW != 0 {
-		ev.W = make([][]uint64, len(t.window))
-		for i := range ev.W {
-			ev.W[i] = make([]uint64, len(t.window[i]))
-			copy(ev.W[i], t.window[i])
-		}
-	} else {
-		ev.W = t.window
-	}
-	if flag&FlagTrace != 0 {
-		ev.T = tr
-	} else {
-		ev.T = nil
+// FillOutput fills `out` with the results of
+// the last simulation.
+func (t *T) FillOutput(out *reach.Output) {
+	for i := range t.watches {
+		out.AppendResult(t.result(i))
 	}
 }
 
-func (t *T) execEvent(m z.Lit, i int, tr *reach.Trace) bool {
-	ch := t.opts.EventChan
-	if ch == nil {
-		return true
-	}
-	if t.opts.Verbose {
-		fmt.Printf("[sim] executing watch event over channel.\n")
-		defer fmt.Printf("[sim] done executing watch event.\n")
-	}
-	ev := &Event{}
-	t.fillEvent(m, i, tr, ev)
-	flag := t.opts.EventFlags
-	if flag&FlagWait == 0 {
-		select {
-		case ch <- ev:
-			if flag&FlagRoundTrip == 0 {
-				return true
-			}
-			ev, ok := <-ch
-			if !ok {
-				return false
-			}
-			flag = ev.F
-			if flag&FlagStop != 0 {
-				close(ch)
-				t.opts.EventChan = nil
-				return false
-			}
-		default:
-			return true
-		}
-	}
-	ch <- ev
-	if flag&FlagRoundTrip == 0 {
-		return true
+// SetOutput sets an output in which the result for each watch is stored as
+// soon as the watch is first reached, so that it is kept even if the process
+// is stopped before Simulate returns.
+func (t *T) SetOutput(out *reach.Output) {
+	t.out = out
+}
+
+// Err returns the first error storing a result in the output given to
+// SetOutput, if any.
+func (t *T) Err() error {
+	return t.err
+}
+
+func (t *T) storeResult(i int) {
+	if t.out == nil {
+		return
 	}
-	ev = <-ch
-	t.opts.EventFlags = ev.F
-	if ev.F&FlagStop != 0 {
-		close(ch)
-		t.opts.EventChan = nil
-		return false
+	// t.ttlSteps does not count the steps of the current simulation yet.
+	r := t.result(i)
+	r.Stats["SimSteps"] = t.ttlSteps + t.steps
+	if err := t.out.StoreResult(r); err != nil && t.err == nil {
+		t.err = err
 	}
-	return true
 }
 
-// FillOutput fills `out` with the results of
-// the last simulation.
-func (t *T) FillOutput(out *reach.Output) {
-	for i, w := range t.watches {
-		b := &reach.Result{M: w}
-		tr := t.traces[i]
-		d := t.depths[i]
-		if d != -1 {
-			b.Depth = int(d) // TBD(wsc) overflow
-			b.SetReachable(tr)
-		}
-		out.AppendResult(b)
+func (t *T) result(i int) *reach.Result {
+	b := &reach.Result{M: t.watches[i], Engine: "sim", Seed: t.opts.Seed}
+	b.Stats = map[string]int64{"SimSteps": t.ttlSteps}
+	tr := t.traces[i]
+	d := t.depths[i]
+	if d != -1 {
+		b.Depth = int(d) // TBD(wsc) overflow
+		b.SetReachable(tr)
 	}
+	return b
 }
 
 func (t *T) genTrace(w z.Lit, s uint) *reach.Trace {
@@ -20,9 +20,12 @@
 package sim_test
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/go-air/reach"
 	"github.com/go-air/reach/sim"
 
 	"github.com/go-air/gini/logic"
@@ -60,13 +63,18 @@ func TestSimEvents(t *testing.T) {
 	s := sim.New(trans, carry)
 	opts := sim.NewOptions()
 	opts.WatchUntil = 100
-	opts.EventChan = make(chan *sim.Event)
+	ch := make(chan *sim.Event)
+	opts.EventChan = ch
 	opts.EventFlags = sim.FlagRoundTrip | sim.FlagWait
 	opts.Duration = time.Hour
 	s.SetOptions(opts)
-	go s.Simulate()
+	done := make(chan struct{})
+	go func() {
+		s.Simulate()
+		close(done)
+	}()
 	for i := 0; i < 10; i++ {
-		ev := <-opts.EventChan
+		ev := <-ch
 		if Log {
 			t.Logf("got event %s\n", ev)
 		}
@@ -76,13 +84,168 @@ func TestSimEvents(t *testing.T) {
 				t.Logf("\t%s: %t\n", m, v)
 			}
 		}
-		opts.EventChan <- ev
+		ch <- ev
 	}
-	ev := <-opts.EventChan
+	ev := <-ch
 	ev.F = sim.FlagStop
-	opts.EventChan <- ev
-	ev, ok := <-opts.EventChan
+	ch <- ev
+	ev, ok := <-ch
 	if ok {
 		t.Errorf("didn't close after stop")
 	}
+	<-done
+	if opts.EventChan != nil {
+		t.Errorf("closed channel kept in options")
+	}
+	// simulating again with the same options does not send on the closed
+	// channel.
+	opts.Duration = 10 * time.Millisecond
+	s.Simulate()
+}
+
+type testObserver struct {
+	watches int
+	steps   int
+	paused  chan sim.Lane
+}
+
+func (o *testObserver) OnWatch(s *sim.T, m z.Lit, l sim.Lane) sim.Action {
+	o.watches++
+	if !l.Value(m) {
+		return sim.Stop
+	}
+	o.paused <- l
+	return sim.Pause
+}
+
+func (o *testObserver) OnStep(s *sim.T) sim.Action {
+	o.steps++
+	return sim.Continue
+}
+
+func (o *testObserver) OnRestart(s *sim.T, n int) sim.Action {
+	return sim.Continue
+}
+
+func TestSimObserver(t *testing.T) {
+	trans := logic.NewS()
+	in := trans.Lit()
+	m := trans.Latch(trans.F)
+	trans.SetNext(m, trans.Choice(in, m.Not(), m))
+	s := sim.New(trans, m)
+	obs := &testObserver{paused: make(chan sim.Lane)}
+	opts := sim.NewOptions()
+	opts.WatchUntil = 1 << 20
+	opts.Duration = time.Hour
+	opts.Observer = obs
+	opts.StepEvery = 1
+	s.SetOptions(opts)
+	done := make(chan int64)
+	go func() {
+		done <- s.Simulate()
+	}()
+	for i := 0; i < 3; i++ {
+		l := <-obs.paused
+		if l.Index() < 0 || l.Index() >= 64 {
+			t.Errorf("lane index %d", l.Index())
+		}
+		s.Resume(sim.Continue)
+	}
+	<-obs.paused
+	s.Resume(sim.Stop)
+	<-done
+	if obs.watches != 4 {
+		t.Errorf("got %d watches not 4", obs.watches)
+	}
+	if obs.steps == 0 {
+		t.Errorf("no steps observed")
+	}
+}
+
+func TestSimStop(t *testing.T) {
+	trans := logic.NewS()
+	m := trans.Latch(trans.F)
+	s := sim.New(trans, m)
+	opts := sim.NewOptions()
+	opts.Duration = time.Hour
+	opts.MaxDepth = 1 << 62
+	s.SetOptions(opts)
+	done := make(chan int64)
+	go func() {
+		done <- s.Simulate()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.Stop()
+	select {
+	case n := <-done:
+		if n == 0 {
+			t.Errorf("no steps before stop")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("simulation not stopped")
+	}
+}
+
+func TestSimStoreStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	trans := logic.NewS()
+	in := trans.Lit()
+	m := trans.Latch(trans.F)
+	trans.SetNext(m, in)
+	out, err := reach.MakeOutputSys(trans, "sim", dir, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := sim.New(trans, m)
+	opts := sim.NewOptions()
+	opts.Duration = time.Second
+	opts.WatchUntil = 1
+	s.SetOptions(opts)
+	s.SetOutput(out)
+	s.Simulate()
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	o, err := reach.OpenOutput(out.RootDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	rs := o.Results()
+	if len(rs) != 1 || !rs[0].IsReachable() {
+		t.Fatalf("unexpected results %v", rs)
+	}
+	if n := rs[0].Stats["SimSteps"]; n == 0 {
+		t.Errorf("stored result has no steps")
+	}
+}
+
+func TestSimStopPaused(t *testing.T) {
+	trans := logic.NewS()
+	in := trans.Lit()
+	m := trans.Latch(trans.F)
+	trans.SetNext(m, trans.Choice(in, m.Not(), m))
+	s := sim.New(trans, m)
+	obs := &testObserver{paused: make(chan sim.Lane, 1)}
+	opts := sim.NewOptions()
+	opts.Duration = time.Hour
+	opts.Observer = obs
+	s.SetOptions(opts)
+	done := make(chan int64)
+	go func() {
+		done <- s.Simulate()
+	}()
+	<-obs.paused
+	s.Stop()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("paused simulation not stopped")
+	}
+	// Resume after Stop does not block.
+	s.Resume(sim.Continue)
 }
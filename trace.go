@@ -77,7 +77,7 @@ func NewTraceLen(s *logic.S, sLen int, ws ...z.Lit) *Trace {
 // Since the unroller only contains cone of influence portion of a sequential
 // circuit, the undefined values are taken by simulation.  The model is checked
 // to be coherent with the simulation.  A non-nil error is returned iff there
-// is incoherence.
+// is incoherence, in which case the errors wrap ErrTraceIncoherent.
 func NewTraceBmc(u *logic.Roll, model inter.Model, ws ...z.Lit) (*Trace, []error) {
 	res := NewTrace(u.S, ws...)
 	N := u.MaxLen()
@@ -120,7 +120,7 @@ func NewTraceBmc(u *logic.Roll, model inter.Model, ws ...z.Lit) (*Trace, []error
 				if model.Value(u.At(nxt, d)) != t {
 					// this actually can happen if an input not in the COI was set to false
 					// needs investigation...
-					fmt.Printf("depth %d latch %s nxt %s vsA[nxt]=%t C.T=%s At(nxt, %d)=%s len(nxt)=%d len(m)=%d\n", d, m,
+					Logf("depth %d latch %s nxt %s vsA[nxt]=%t C.T=%s At(nxt, %d)=%s len(nxt)=%d len(m)=%d\n", d, m,
 						nxt, t, u.C.T, d, u.At(nxt, d), u.Len(nxt), u.Len(m))
 				}
 			}
@@ -175,11 +175,51 @@ func (t *Trace) Append(vs []bool) {
 	t.n++
 }
 
+// mapLits returns a copy of `t` whose input, latch and watch literals are
+// mapped by `f`.
+func (t *Trace) mapLits(f func(z.Lit) z.Lit) *Trace {
+	res := &Trace{n: t.n, values: append([]bool(nil), t.values...)}
+	for _, m := range t.Inputs {
+		res.Inputs = append(res.Inputs, f(m))
+	}
+	for _, m := range t.Latches {
+		res.Latches = append(res.Latches, f(m))
+	}
+	for _, m := range t.Watches {
+		res.Watches = append(res.Watches, f(m))
+	}
+	return res
+}
+
 // Len returns the number of states in the trace.
 func (t *Trace) Len() int {
 	return t.n
 }
 
+// TraceSize gives the length of a Trace and the number of its input
+// values which are true.
+type TraceSize struct {
+	Len    int
+	Inputs int
+}
+
+func (sz TraceSize) String() string {
+	return fmt.Sprintf("length %d, %d true inputs", sz.Len, sz.Inputs)
+}
+
+// Size returns the size of `t`.
+func (t *Trace) Size() TraceSize {
+	sz := TraceSize{Len: t.n}
+	for d := 0; d < t.n; d++ {
+		for i := range t.Inputs {
+			if t.InputVal(i, d) {
+				sz.Inputs++
+			}
+		}
+	}
+	return sz
+}
+
 // InputVal returns the truth value for input with index i at
 // depth `depth`.
 func (t *Trace) InputVal(i, depth int) bool {
@@ -210,7 +250,7 @@ func (t *Trace) WatchVal(i, depth int) bool {
 //
 // Verify returns a non nil error describing a latch or watch in a bad
 // state in the trace with respect to s iff there is such
-// a latch or watch.
+// a latch or watch.  The returned errors wrap ErrTraceIncoherent.
 //
 // Verify may panic if the trace is not dimensioned according to `s`.
 // Namely, if the latches in `t` are not latches in `s`, or likewise
@@ -239,7 +279,7 @@ func (t *Trace) Verify(s *logic.S) []error {
 			wv = !wv
 		}
 		if !wv {
-			errors = append(errors, fmt.Errorf("ErrWatchFalse: %s", m))
+			errors = append(errors, fmt.Errorf("%w: watch %s never true", ErrTraceIncoherent, m))
 		}
 	}
 	return errors
@@ -268,11 +308,11 @@ func newTv(t *Trace, s *logic.S) (*tv, error) {
 	}
 	// check dimensions
 	if len(t.Latches) != len(s.Latches) {
-		return nil, fmt.Errorf("ErrLatchCount: got %d not %d", len(s.Latches), len(t.Latches))
+		return nil, fmt.Errorf("%w: latch count %d not %d", ErrTraceIncoherent, len(t.Latches), len(s.Latches))
 	}
 	for _, m := range t.Latches {
 		if s.Type(m) != logic.SLatch {
-			return nil, fmt.Errorf("ErrNotLatch: %s %s", m, s.Type(m))
+			return nil, fmt.Errorf("%w: %s is %s not latch", ErrTraceIncoherent, m, s.Type(m))
 		}
 	}
 	j := 0
@@ -283,13 +323,13 @@ func newTv(t *Trace, s *logic.S) (*tv, error) {
 			continue
 		}
 		if j >= len(t.Inputs) {
-			return nil, fmt.Errorf("ErrInputCount: too many inputs for trace: %d > %d",
+			return nil, fmt.Errorf("%w: too many inputs for trace: %d > %d", ErrTraceIncoherent,
 				j+1, j)
 		}
 		j++
 	}
 	if j < len(t.Inputs) {
-		return nil, fmt.Errorf("ErrInputCount: not enough inputs for trace: %d < %d",
+		return nil, fmt.Errorf("%w: not enough inputs for trace: %d < %d", ErrTraceIncoherent,
 			j, len(t.Inputs))
 	}
 
@@ -301,11 +341,11 @@ func newTv(t *Trace, s *logic.S) (*tv, error) {
 		switch s.Init(m) {
 		case s.T:
 			if !t {
-				return nil, fmt.Errorf("latch %s set to %t but initialised to %t\n", m, t, true)
+				return nil, fmt.Errorf("%w: latch %s set to %t but initialised to %t", ErrTraceIncoherent, m, t, true)
 			}
 		case s.F:
 			if t {
-				return nil, fmt.Errorf("latch %s set to %t but initialised to %t\n", m, t, false)
+				return nil, fmt.Errorf("%w: latch %s set to %t but initialised to %t", ErrTraceIncoherent, m, t, false)
 			}
 		}
 		vs[m.Var()] = t
@@ -328,9 +368,9 @@ func newTv(t *Trace, s *logic.S) (*tv, error) {
 		}
 		if mval != vs[m.Var()] {
 			if !m.IsPos() {
-				return nil, fmt.Errorf("watch %s at 0 got %t not %t\n", m, !mval, !vs[m.Var()])
+				return nil, fmt.Errorf("%w: watch %s at 0 got %t not %t", ErrTraceIncoherent, m, !mval, !vs[m.Var()])
 			}
-			return nil, fmt.Errorf("watch %s at 0 got %t not %t\n", m, mval, vs[m.Var()])
+			return nil, fmt.Errorf("%w: watch %s at 0 got %t not %t", ErrTraceIncoherent, m, mval, vs[m.Var()])
 		}
 	}
 	return res, nil
@@ -350,7 +390,7 @@ func (tv *tv) step(d int) error {
 			nv = !nv
 		}
 		if t != nv {
-			return fmt.Errorf("at %d latch %s (@%d) nxt %s got %t not %t\n", d, m, i, nxt, nv, t)
+			return fmt.Errorf("%w: at %d latch %s (@%d) nxt %s got %t not %t", ErrTraceIncoherent, d, m, i, nxt, nv, t)
 		}
 		vsB[m.Var()] = t
 	}
@@ -373,7 +413,7 @@ func (tv *tv) step(d int) error {
 			ref = !ref
 		}
 		if ref != t {
-			return fmt.Errorf("at %d, watch %s got %t not %t\n", d, m, ref, t)
+			return fmt.Errorf("%w: at %d, watch %s got %t not %t", ErrTraceIncoherent, d, m, ref, t)
 		}
 	}
 
@@ -454,8 +494,7 @@ func DecodeTrace(r io.Reader) (*Trace, error) {
 	var err error
 	_, err = fmt.Fscanf(r, "trace %d %d %d %d\n", &trace.n, &nIn, &nL, &nW)
 	if err != nil {
-		fmt.Printf("header\n")
-		return nil, err
+		return nil, fmt.Errorf("trace header: %w", err)
 	}
 	trace.Inputs = make([]z.Lit, nIn)
 	trace.Latches = make([]z.Lit, nL)
@@ -468,8 +507,7 @@ func DecodeTrace(r io.Reader) (*Trace, error) {
 			_, err = fmt.Fscanf(r, "%d\n", &u)
 		}
 		if err != nil {
-			fmt.Printf("input %d/%d\n", i, nIn)
-			return nil, err
+			return nil, fmt.Errorf("trace input %d/%d: %w", i, nIn, err)
 		}
 		trace.Inputs[i] = z.Var(u).Pos()
 	}
@@ -480,8 +518,7 @@ func DecodeTrace(r io.Reader) (*Trace, error) {
 			_, err = fmt.Fscanf(r, "%d\n", &u)
 		}
 		if err != nil {
-			fmt.Printf("latch %d\n", i)
-			return nil, err
+			return nil, fmt.Errorf("trace latch %d/%d: %w", i, nL, err)
 		}
 		trace.Latches[i] = z.Var(u).Pos()
 	}
@@ -492,8 +529,7 @@ func DecodeTrace(r io.Reader) (*Trace, error) {
 			_, err = fmt.Fscanf(r, "%d\n", &u)
 		}
 		if err != nil {
-			fmt.Printf("watch %d\n", i)
-			return nil, err
+			return nil, fmt.Errorf("trace watch %d/%d: %w", i, nW, err)
 		}
 		trace.Watches[i] = z.Lit(u)
 	}
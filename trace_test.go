@@ -5,12 +5,15 @@ package reach
 
 import (
 	"bytes"
+	"errors"
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/logic/aiger"
 	"github.com/go-air/gini/z"
 )
 
@@ -152,6 +155,8 @@ func TestTraceVerifyInvalidInit(t *testing.T) {
 	err := tr.Verify(s)
 	if err == nil {
 		t.Errorf("verified invalid init")
+	} else if !errors.Is(err[0], ErrTraceIncoherent) {
+		t.Errorf("got err %s, expected %s", err[0], ErrTraceIncoherent)
 	} else {
 		t.Logf("correctly found err %s", err)
 	}
@@ -254,3 +259,146 @@ func TestTraceIO(t *testing.T) {
 		}
 	}
 }
+
+func TestTraceVCD(t *testing.T) {
+	s, n, carry, ms := gen()
+	tr := NewTrace(s, carry)
+	vsA, vsB := make([]bool, s.Len()), make([]bool, s.Len())
+	for i := 0; i < 1<<3; i++ {
+		vsA[n.Var()] = true
+		s.Eval(vsA)
+		tr.Append(vsA)
+		for _, m := range ms {
+			nxt := s.Next(m)
+			t := vsA[nxt.Var()]
+			if !nxt.IsPos() {
+				t = !t
+			}
+			vsB[m.Var()] = t
+		}
+		vsA, vsB = vsB, vsA
+	}
+	names := map[z.Var]string{n.Var(): "the input"}
+	w := bytes.NewBuffer(nil)
+	if err := tr.EncodeVCD(w, names, s); err != nil {
+		t.Fatal(err)
+	}
+	out := w.String()
+	for _, want := range []string{"the_input", "$scope module gates $end", "$enddefinitions $end", "#8\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("vcd output missing %q", want)
+		}
+	}
+}
+
+func TestAigerNames(t *testing.T) {
+	// a latch q following the input, with output !q.
+	g, err := aiger.ReadAscii(strings.NewReader(
+		"aag 2 1 1 1 0\n2\n4 2\n5\ni0 in\nl0 q\no0 nq\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := AigerNames(g)
+	if nm := names[g.Latches[0].Var()]; nm != "q" {
+		t.Errorf("latch named %q not q", nm)
+	}
+	s := g.S
+	tr := NewTrace(s, g.Outputs[0])
+	vs := make([]bool, s.Len())
+	for _, v := range []bool{true, false} {
+		vs[g.Inputs[0].Var()] = v
+		s.Eval(vs)
+		tr.Append(vs)
+		vs[g.Latches[0].Var()] = v
+	}
+	w := bytes.NewBuffer(nil)
+	if err := tr.EncodeVCD(w, names, s); err != nil {
+		t.Fatal(err)
+	}
+	out := w.String()
+	for _, want := range []string{" q $end", " not_q $end"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("vcd output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "nq") {
+		t.Errorf("vcd output names the latch by its negated output:\n%s", out)
+	}
+}
+
+func TestTraceJSONText(t *testing.T) {
+	s, n, carry, ms := gen()
+	tr := NewTrace(s, carry, carry.Not())
+	vs := make([]bool, s.Len())
+	for i := 0; i < 5; i++ {
+		vs[n.Var()] = i%2 == 0
+		vs[ms[0].Var()] = i%3 == 0
+		s.Eval(vs)
+		tr.Append(vs)
+	}
+	names := map[z.Var]string{n.Var(): "n", ms[0].Var(): "n"}
+	for _, f := range []TraceFormat{TraceBinary, TraceJSON, TraceText} {
+		w := bytes.NewBuffer(nil)
+		if err := tr.EncodeFormat(w, f, names); err != nil {
+			t.Fatal(err)
+		}
+		ttr, err := DecodeTraceFormat(w, f)
+		if err != nil {
+			t.Fatalf("%s: %s", f, err)
+		}
+		if ttr.Len() != tr.Len() {
+			t.Errorf("%s: len %d not %d", f, ttr.Len(), tr.Len())
+		}
+		for i, m := range tr.Watches {
+			if ttr.Watches[i] != m {
+				t.Errorf("%s: watch %d got %s not %s", f, i, ttr.Watches[i], m)
+			}
+		}
+		for i, v := range tr.values {
+			if ttr.values[i] != v {
+				t.Errorf("%s: val %d got %t not %t", f, i, ttr.values[i], v)
+			}
+		}
+	}
+}
+
+func TestTraceAigerWitness(t *testing.T) {
+	s, n, carry, _ := gen()
+	g := aiger.MakeFor(s)
+	g.Bad = append(g.Bad, carry)
+	tr := NewTrace(s, carry)
+	vsA, vsB := make([]bool, s.Len()), make([]bool, s.Len())
+	for i := 0; i < 1<<3; i++ {
+		vsA[n.Var()] = true
+		s.Eval(vsA)
+		tr.Append(vsA)
+		stepLatches(s, vsA, vsB)
+		vsA, vsB = vsB, vsA
+	}
+	w := bytes.NewBuffer(nil)
+	if err := tr.EncodeAigerWitness(w, g, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(w.String(), "1\nb0\n000\n1\n") {
+		t.Errorf("unexpected witness:\n%s", w.String())
+	}
+	wit, err := DecodeAigerWitness(bytes.NewBufferString("c other tool\n"+w.String()), g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wit.Status != 1 || len(wit.Props) != 1 || wit.Props[0] != 0 {
+		t.Errorf("bad witness status %d props %v", wit.Status, wit.Props)
+	}
+	if wit.Trace.Len() != tr.Len() {
+		t.Errorf("witness trace len %d not %d", wit.Trace.Len(), tr.Len())
+	}
+	if errs := wit.Trace.Verify(s); len(errs) != 0 {
+		t.Error(errs)
+	}
+	for _, p := range []int{-1, 1} {
+		w.Reset()
+		if err := tr.EncodeAigerWitness(w, g, p); err == nil || w.Len() != 0 {
+			t.Errorf("property %d: got %v and witness:\n%s", p, err, w.String())
+		}
+	}
+}
@@ -0,0 +1,389 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-air/gini/z"
+)
+
+// TraceFormat identifies an encoding of a Trace.
+type TraceFormat int
+
+const (
+	// TraceBinary is the format written by Trace.Encode.
+	TraceBinary TraceFormat = iota
+	// TraceJSON is the format written by Trace.EncodeJSON.
+	TraceJSON
+	// TraceText is the format written by Trace.EncodeText.
+	TraceText
+)
+
+// TraceVersion is the version of the JSON and text trace formats.
+const TraceVersion = 1
+
+var traceFormatNames = [...]string{"bin", "json", "text"}
+
+var traceFormatExts = [...]string{".trace", ".trace.json", ".trace.txt"}
+
+func (f TraceFormat) String() string {
+	return traceFormatNames[f]
+}
+
+// Ext returns the file name extension used by Output for traces in format
+// `f`.
+func (f TraceFormat) Ext() string {
+	return traceFormatExts[f]
+}
+
+// ParseTraceFormat returns the TraceFormat whose String() is `s`.
+func ParseTraceFormat(s string) (TraceFormat, error) {
+	for i, nm := range traceFormatNames {
+		if nm == s {
+			return TraceFormat(i), nil
+		}
+	}
+	return TraceBinary, fmt.Errorf("unknown trace format '%s'", s)
+}
+
+// EncodeFormat encodes `t` to `w` in format `f`, with signal names from
+// `names` for the formats that have them.
+func (t *Trace) EncodeFormat(w io.Writer, f TraceFormat, names map[z.Var]string) error {
+	switch f {
+	case TraceBinary:
+		return t.Encode(w)
+	case TraceJSON:
+		return t.EncodeJSON(w, names)
+	case TraceText:
+		return t.EncodeText(w, names)
+	default:
+		panic("unreachable")
+	}
+}
+
+// DecodeTraceFormat decodes a trace in format `f` from `r`.
+func DecodeTraceFormat(r io.Reader, f TraceFormat) (*Trace, error) {
+	switch f {
+	case TraceBinary:
+		return DecodeTrace(r)
+	case TraceJSON:
+		return DecodeTraceJSON(r)
+	case TraceText:
+		return DecodeTraceText(r)
+	default:
+		panic("unreachable")
+	}
+}
+
+// TraceSignal names a literal of a trace in the JSON format.
+type TraceSignal struct {
+	Name string
+	Lit  z.Lit
+}
+
+type jsonTrace struct {
+	Version int
+	Len     int
+	Inputs  []TraceSignal
+	Latches []TraceSignal
+	Watches []TraceSignal
+	Steps   []map[string]bool
+}
+
+// EncodeJSON writes `t` as a JSON object with a version, the signals of the
+// trace and a list of steps, each step mapping signal names to values.
+//
+// Signals are named by `names`, which may be nil, and otherwise by their kind
+// and variable.  Names are made unique.
+func (t *Trace) EncodeJSON(w io.Writer, names map[z.Var]string) error {
+	ins, ls, ws := t.signalNames(names)
+	jt := &jsonTrace{
+		Version: TraceVersion,
+		Len:     t.n,
+		Inputs:  signals(t.Inputs, ins),
+		Latches: signals(t.Latches, ls),
+		Watches: signals(t.Watches, ws),
+		Steps:   make([]map[string]bool, t.n)}
+	for d := 0; d < t.n; d++ {
+		step := make(map[string]bool, len(ins)+len(ls)+len(ws))
+		for i, nm := range ins {
+			step[nm] = t.InputVal(i, d)
+		}
+		for i, nm := range ls {
+			step[nm] = t.LatchVal(i, d)
+		}
+		for i, nm := range ws {
+			step[nm] = t.WatchVal(i, d)
+		}
+		jt.Steps[d] = step
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(jt)
+}
+
+// DecodeTraceJSON reads a trace as written by EncodeJSON.
+func DecodeTraceJSON(r io.Reader) (*Trace, error) {
+	jt := &jsonTrace{}
+	if err := json.NewDecoder(r).Decode(jt); err != nil {
+		return nil, err
+	}
+	if jt.Version != TraceVersion {
+		return nil, fmt.Errorf("unsupported trace version %d", jt.Version)
+	}
+	if jt.Len != len(jt.Steps) {
+		return nil, fmt.Errorf("trace length %d but %d steps", jt.Len, len(jt.Steps))
+	}
+	t := &Trace{}
+	var sigs []TraceSignal
+	t.Inputs, sigs = lits(t.Inputs, sigs, jt.Inputs)
+	t.Latches, sigs = lits(t.Latches, sigs, jt.Latches)
+	t.Watches, sigs = lits(t.Watches, sigs, jt.Watches)
+	t.values = make([]bool, 0, len(sigs)*jt.Len)
+	for d, step := range jt.Steps {
+		for _, sig := range sigs {
+			v, ok := step[sig.Name]
+			if !ok {
+				return nil, fmt.Errorf("step %d: missing value for %s", d, sig.Name)
+			}
+			t.values = append(t.values, v)
+		}
+		t.n++
+	}
+	return t, nil
+}
+
+// EncodeText writes `t` as whitespace separated, column aligned text.  After
+// a version line, there are header lines giving the kind ('i', 'l', or 'w'),
+// literal and name of every signal, followed by one line per step of 0s and
+// 1s.
+//
+// Signals are named as in EncodeJSON.
+func (t *Trace) EncodeText(w io.Writer, names map[z.Var]string) error {
+	ins, ls, ws := t.signalNames(names)
+	kinds := make([]string, 0, len(ins)+len(ls)+len(ws))
+	nms := make([]string, 0, cap(kinds))
+	ms := make([]string, 0, cap(kinds))
+	for i, nm := range ins {
+		kinds = append(kinds, "i")
+		nms = append(nms, nm)
+		ms = append(ms, strconv.Itoa(int(t.Inputs[i])))
+	}
+	for i, nm := range ls {
+		kinds = append(kinds, "l")
+		nms = append(nms, nm)
+		ms = append(ms, strconv.Itoa(int(t.Latches[i])))
+	}
+	for i, nm := range ws {
+		kinds = append(kinds, "w")
+		nms = append(nms, nm)
+		ms = append(ms, strconv.Itoa(int(t.Watches[i])))
+	}
+	first := len(strconv.Itoa(t.n))
+	if first < 4 {
+		first = 4
+	}
+	widths := make([]int, len(nms))
+	for i := range widths {
+		widths[i] = len(nms[i])
+		if len(ms[i]) > widths[i] {
+			widths[i] = len(ms[i])
+		}
+	}
+	bw := bufio.NewWriter(w)
+	row := func(hd string, cols []string) {
+		fmt.Fprintf(bw, "%-*s", first, hd)
+		for i, c := range cols {
+			fmt.Fprintf(bw, " %*s", widths[i], c)
+		}
+		fmt.Fprintf(bw, "\n")
+	}
+	fmt.Fprintf(bw, "reach-trace %d %d\n", TraceVersion, t.n)
+	row("kind", kinds)
+	row("lit", ms)
+	row("name", nms)
+	vals := make([]string, len(nms))
+	sz := len(nms)
+	for d := 0; d < t.n; d++ {
+		for i, v := range t.values[d*sz : d*sz+sz] {
+			if v {
+				vals[i] = "1"
+			} else {
+				vals[i] = "0"
+			}
+		}
+		row(strconv.Itoa(d), vals)
+	}
+	return bw.Flush()
+}
+
+// DecodeTraceText reads a trace as written by EncodeText.
+func DecodeTraceText(r io.Reader) (*Trace, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<30)
+	line := 0
+	next := func(hd string) ([]string, error) {
+		if !sc.Scan() {
+			if err := sc.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.ErrUnexpectedEOF
+		}
+		line++
+		fs := strings.Fields(sc.Text())
+		if len(fs) == 0 || (hd != "" && fs[0] != hd) {
+			return nil, fmt.Errorf("line %d: expected '%s'", line, hd)
+		}
+		return fs[1:], nil
+	}
+	fs, err := next("reach-trace")
+	if err != nil {
+		return nil, err
+	}
+	if len(fs) != 2 {
+		return nil, fmt.Errorf("line %d: bad version line", line)
+	}
+	if v, err := strconv.Atoi(fs[0]); err != nil || v != TraceVersion {
+		return nil, fmt.Errorf("unsupported trace version %s", fs[0])
+	}
+	n, err := strconv.Atoi(fs[1])
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", line, err)
+	}
+	kinds, err := next("kind")
+	if err != nil {
+		return nil, err
+	}
+	ms, err := next("lit")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := next("name"); err != nil {
+		return nil, err
+	}
+	if len(ms) != len(kinds) {
+		return nil, fmt.Errorf("line %d: %d literals for %d kinds", line, len(ms), len(kinds))
+	}
+	t := &Trace{}
+	for i, k := range kinds {
+		u, err := strconv.ParseUint(ms[i], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("lit %d: %w", i, err)
+		}
+		m := z.Lit(u)
+		switch k {
+		case "i":
+			t.Inputs = append(t.Inputs, m)
+		case "l":
+			t.Latches = append(t.Latches, m)
+		case "w":
+			t.Watches = append(t.Watches, m)
+		default:
+			return nil, fmt.Errorf("unknown signal kind '%s'", k)
+		}
+	}
+	if len(t.Inputs)+len(t.Latches)+len(t.Watches) != len(kinds) ||
+		!sorted(kinds) {
+		return nil, fmt.Errorf("signals not ordered as inputs, latches, watches")
+	}
+	t.values = make([]bool, 0, len(kinds)*n)
+	for d := 0; d < n; d++ {
+		vs, err := next(strconv.Itoa(d))
+		if err != nil {
+			return nil, err
+		}
+		if len(vs) != len(kinds) {
+			return nil, fmt.Errorf("line %d: %d values for %d signals", line, len(vs), len(kinds))
+		}
+		for _, v := range vs {
+			switch v {
+			case "0":
+				t.values = append(t.values, false)
+			case "1":
+				t.values = append(t.values, true)
+			default:
+				return nil, fmt.Errorf("line %d: bad value '%s'", line, v)
+			}
+		}
+		t.n++
+	}
+	return t, nil
+}
+
+func sorted(kinds []string) bool {
+	for i := 1; i < len(kinds); i++ {
+		if kinds[i] < kinds[i-1] {
+			return false
+		}
+	}
+	return true
+}
+
+func signals(ms []z.Lit, nms []string) []TraceSignal {
+	res := make([]TraceSignal, len(ms))
+	for i, m := range ms {
+		res[i] = TraceSignal{Name: nms[i], Lit: m}
+	}
+	return res
+}
+
+func lits(dst []z.Lit, all, sigs []TraceSignal) ([]z.Lit, []TraceSignal) {
+	for _, sig := range sigs {
+		dst = append(dst, sig.Lit)
+	}
+	return dst, append(all, sigs...)
+}
+
+// signalNames returns unique names for the inputs, latches and watches of
+// `t`, taken from `names` where available.
+func (t *Trace) signalNames(names map[z.Var]string) (ins, ls, ws []string) {
+	seen := make(map[string]bool)
+	name := func(m z.Lit, pfx string) string {
+		nm := sigName(names, m, pfx)
+		for i := 1; seen[nm]; i++ {
+			nm = fmt.Sprintf("%s_%d", sigName(names, m, pfx), i)
+		}
+		seen[nm] = true
+		return nm
+	}
+	ins = make([]string, len(t.Inputs))
+	for i, m := range t.Inputs {
+		ins[i] = name(m, "i")
+	}
+	ls = make([]string, len(t.Latches))
+	for i, m := range t.Latches {
+		ls[i] = name(m, "l")
+	}
+	ws = make([]string, len(t.Watches))
+	for i, m := range t.Watches {
+		ws[i] = name(m, "w")
+	}
+	return
+}
+
+// sigName gives the name of `m` from `names`, or else `pfx` followed
+// by the variable of `m`, with whitespace and non printable characters
+// replaced.
+func sigName(names map[z.Var]string, m z.Lit, pfx string) string {
+	nm, ok := names[m.Var()]
+	if !ok {
+		nm = fmt.Sprintf("%s%d", pfx, m.Var())
+	}
+	if !m.IsPos() {
+		nm = "not_" + nm
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, nm)
+}
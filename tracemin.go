@@ -0,0 +1,167 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/z"
+)
+
+// MinimizeTrace returns a trace of `s` which, like `tr`, makes every watch
+// of `tr` true at some point, and which is no longer than `tr` and sets no
+// more inputs to true.  `tr` should verify with Trace.Verify.
+//
+// First, the trace is cut after the first state in which all watches have
+// been true.  Then each true input, and each true initial value of a latch
+// without a fixed initial value, is set to false if the watches are still
+// reached, cutting the trace again when they are reached earlier.  Each
+// step is checked by simulation, so the result is minimal in the sense that
+// no single true value can be set to false.
+//
+// If time runs out after `dur`, MinimizeTrace returns the partially
+// minimized trace, which is valid, together with ErrTimeout.
+func MinimizeTrace(s *logic.S, tr *Trace, dur time.Duration) (*Trace, error) {
+	if len(tr.Watches) == 0 {
+		return nil, fmt.Errorf("%w: trace has no watches", ErrTraceIncoherent)
+	}
+	if errs := tr.Verify(s); len(errs) != 0 {
+		return nil, errs[0]
+	}
+	tm := newTraceMin(s, tr)
+	deadline := time.Now().Add(dur)
+	tm.cut(tm.reach())
+	for i, m := range tr.Latches {
+		if s.Init(m) == s.T || s.Init(m) == s.F || !tm.init[i] {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return tm.trace(), ErrTimeout
+		}
+		tm.init[i] = false
+		if n := tm.reach(); n > 0 {
+			tm.cut(n)
+		} else {
+			tm.init[i] = true
+		}
+	}
+	for d := 0; d < len(tm.ins); d++ {
+		for i := range tm.ins[d] {
+			if !tm.ins[d][i] {
+				continue
+			}
+			if time.Now().After(deadline) {
+				return tm.trace(), ErrTimeout
+			}
+			tm.ins[d][i] = false
+			if n := tm.reach(); n > 0 {
+				tm.cut(n)
+			} else {
+				tm.ins[d][i] = true
+			}
+		}
+	}
+	return tm.trace(), nil
+}
+
+// traceMin holds the state of MinimizeTrace: the initial values of the
+// latches and the inputs at each depth, from which traces are simulated.
+type traceMin struct {
+	s    *logic.S
+	tr   *Trace
+	init []bool
+	ins  [][]bool
+	vs   []bool
+	seen []bool
+}
+
+func newTraceMin(s *logic.S, tr *Trace) *traceMin {
+	tm := &traceMin{
+		s:    s,
+		tr:   tr,
+		init: make([]bool, len(tr.Latches)),
+		ins:  make([][]bool, tr.Len()),
+		vs:   make([]bool, s.Len()),
+		seen: make([]bool, len(tr.Watches))}
+	for i := range tr.Latches {
+		tm.init[i] = tr.LatchVal(i, 0)
+	}
+	for d := range tm.ins {
+		tm.ins[d] = make([]bool, len(tr.Inputs))
+		for i := range tr.Inputs {
+			tm.ins[d][i] = tr.InputVal(i, d)
+		}
+	}
+	return tm
+}
+
+// cut removes the inputs after the first `n` states.
+func (tm *traceMin) cut(n int) {
+	tm.ins = tm.ins[:n]
+}
+
+// reach returns the length of the shortest prefix of the simulated trace in
+// which every watch has been true, or 0 if there is none.
+func (tm *traceMin) reach() int {
+	for i := range tm.seen {
+		tm.seen[i] = false
+	}
+	n := 0
+	tm.sim(func(d int) bool {
+		all := true
+		for i, m := range tm.tr.Watches {
+			v := tm.vs[m.Var()]
+			if !m.IsPos() {
+				v = !v
+			}
+			tm.seen[i] = tm.seen[i] || v
+			all = all && tm.seen[i]
+		}
+		if all {
+			n = d + 1
+		}
+		return !all
+	})
+	return n
+}
+
+// trace returns the simulated trace.
+func (tm *traceMin) trace() *Trace {
+	res := &Trace{
+		Inputs:  append([]z.Lit(nil), tm.tr.Inputs...),
+		Latches: append([]z.Lit(nil), tm.tr.Latches...),
+		Watches: append([]z.Lit(nil), tm.tr.Watches...)}
+	tm.sim(func(_ int) bool {
+		res.Append(tm.vs)
+		return true
+	})
+	return res
+}
+
+// sim simulates the states of the trace in order, calling `f` with the
+// depth of each state once its values are in tm.vs, until `f` returns
+// false.
+func (tm *traceMin) sim(f func(d int) bool) {
+	s, vs := tm.s, tm.vs
+	nxt := make([]bool, len(tm.tr.Latches))
+	copy(nxt, tm.init)
+	for d, ins := range tm.ins {
+		for i, m := range tm.tr.Latches {
+			vs[m.Var()] = nxt[i]
+		}
+		for i, m := range tm.tr.Inputs {
+			vs[m.Var()] = ins[i]
+		}
+		s.Eval(vs)
+		if !f(d) {
+			return
+		}
+		for i, m := range tm.tr.Latches {
+			n := s.Next(m)
+			nxt[i] = vs[n.Var()] == n.IsPos()
+		}
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/z"
+)
+
+// genCounterTrace generates the 3 bit counter of gen with an extra input
+// which is not used, and a trace of length n setting all inputs to true.
+func genCounterTrace(n int) (*logic.S, z.Lit, *Trace) {
+	s, in, carry, ms := gen()
+	noise := s.Lit()
+	tr := NewTrace(s, carry)
+	vsA, vsB := make([]bool, s.Len()), make([]bool, s.Len())
+	for i := 0; i < n; i++ {
+		vsA[in.Var()] = true
+		vsA[noise.Var()] = true
+		s.Eval(vsA)
+		tr.Append(vsA)
+		for _, m := range ms {
+			nxt := s.Next(m)
+			vsB[m.Var()] = vsA[nxt.Var()] == nxt.IsPos()
+		}
+		vsA, vsB = vsB, vsA
+	}
+	return s, carry, tr
+}
+
+func TestMinimizeTrace(t *testing.T) {
+	s, _, tr := genCounterTrace(12)
+	if sz := tr.Size(); sz.Len != 12 || sz.Inputs != 24 {
+		t.Fatalf("trace of size %s", sz)
+	}
+	min, err := MinimizeTrace(s, tr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sz := min.Size(); sz.Len != 8 || sz.Inputs != 8 {
+		t.Errorf("minimized to %s", sz)
+	}
+	if errs := min.Verify(s); len(errs) != 0 {
+		t.Error(errs)
+	}
+
+	min, err = MinimizeTrace(s, tr, 0)
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected %s, got %v", ErrTimeout, err)
+	}
+	if min == nil {
+		t.Fatalf("no partially minimized trace")
+	}
+	if errs := min.Verify(s); len(errs) != 0 {
+		t.Error(errs)
+	}
+
+	tr = NewTrace(s)
+	if _, err := MinimizeTrace(s, tr, time.Second); !errors.Is(err, ErrTraceIncoherent) {
+		t.Errorf("expected %s, got %v", ErrTraceIncoherent, err)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/logic/aiger"
+	"github.com/go-air/gini/z"
+)
+
+// AigerNames returns the symbol names of the inputs, latches, outputs and
+// bad state literals of `g`, indexed by variable, for use with EncodeVCD.
+//
+// Outputs and bad state literals only name their variable if they are
+// positive and the variable is not already named as an input or latch.
+// A negated output such as !q is thus not confused with the latch q; the
+// watch for it is named not_q by EncodeVCD.
+func AigerNames(g *aiger.T) map[z.Var]string {
+	res := make(map[z.Var]string)
+	for i, m := range g.Inputs {
+		if nm, ok := g.InputName(i); ok {
+			res[m.Var()] = nm
+		}
+	}
+	for i, m := range g.Latches {
+		if nm, ok := g.LatchName(i); ok {
+			res[m.Var()] = nm
+		}
+	}
+	named := func(m z.Lit, nm string) {
+		if _, ok := res[m.Var()]; ok || !m.IsPos() {
+			return
+		}
+		res[m.Var()] = nm
+	}
+	for i, m := range g.Outputs {
+		if nm, ok := g.OutputName(i); ok {
+			named(m, nm)
+		}
+	}
+	for i, m := range g.Bad {
+		if nm, ok := g.BadName(i); ok {
+			named(m, nm)
+		}
+	}
+	return res
+}
+
+type vcdVar struct {
+	id   string
+	name string
+	last int8 // -1 unset, 0 false, 1 true
+}
+
+// EncodeVCD writes `t` as a value change dump, one time unit per step, with
+// the inputs, latches and watches of `t` in separate scopes.
+//
+// Variables are named by `names`, which may be nil, and otherwise by their
+// kind and variable index.  If `s` is not nil, then the values of all AND
+// gates in `s` are recomputed with s.Eval and dumped as well.  In that case,
+// `t` should be dimensioned according to `s`, as for Verify.
+func (t *Trace) EncodeVCD(w io.Writer, names map[z.Var]string, s *logic.S) error {
+	bw := bufio.NewWriter(w)
+	nextId := 0
+	newVar := func(nm string) *vcdVar {
+		v := &vcdVar{id: vcdId(nextId), name: nm, last: -1}
+		nextId++
+		return v
+	}
+	inNames, lNames, wNames := t.signalNames(names)
+	ins := make([]*vcdVar, len(t.Inputs))
+	for i := range t.Inputs {
+		ins[i] = newVar(inNames[i])
+	}
+	latches := make([]*vcdVar, len(t.Latches))
+	for i := range t.Latches {
+		latches[i] = newVar(lNames[i])
+	}
+	watches := make([]*vcdVar, len(t.Watches))
+	for i := range t.Watches {
+		watches[i] = newVar(wNames[i])
+	}
+	var gates []z.Lit
+	var gvars []*vcdVar
+	var vs []bool
+	if s != nil {
+		for i := 2; i < s.Len(); i++ {
+			m := z.Var(i).Pos()
+			if s.Type(m) != logic.SAnd {
+				continue
+			}
+			gates = append(gates, m)
+			gvars = append(gvars, newVar(sigName(names, m, "g")))
+		}
+		vs = make([]bool, s.Len())
+	}
+
+	fmt.Fprintf(bw, "$version reach $end\n")
+	fmt.Fprintf(bw, "$timescale 1ns $end\n")
+	fmt.Fprintf(bw, "$scope module trace $end\n")
+	scopes := []struct {
+		name string
+		vars []*vcdVar
+	}{{"inputs", ins}, {"latches", latches}, {"watches", watches}, {"gates", gvars}}
+	for _, sc := range scopes {
+		if len(sc.vars) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "$scope module %s $end\n", sc.name)
+		for _, v := range sc.vars {
+			fmt.Fprintf(bw, "$var wire 1 %s %s $end\n", v.id, v.name)
+		}
+		fmt.Fprintf(bw, "$upscope $end\n")
+	}
+	fmt.Fprintf(bw, "$upscope $end\n")
+	fmt.Fprintf(bw, "$enddefinitions $end\n")
+
+	dump := func(v *vcdVar, b bool) {
+		var c int8
+		if b {
+			c = 1
+		}
+		if c == v.last {
+			return
+		}
+		v.last = c
+		fmt.Fprintf(bw, "%d%s\n", c, v.id)
+	}
+	for d := 0; d < t.n; d++ {
+		fmt.Fprintf(bw, "#%d\n", d)
+		if d == 0 {
+			fmt.Fprintf(bw, "$dumpvars\n")
+		}
+		for i, v := range ins {
+			dump(v, t.InputVal(i, d))
+		}
+		for i, v := range latches {
+			dump(v, t.LatchVal(i, d))
+		}
+		for i, v := range watches {
+			dump(v, t.WatchVal(i, d))
+		}
+		if s != nil {
+			for i, m := range t.Inputs {
+				vs[m.Var()] = t.InputVal(i, d)
+			}
+			for i, m := range t.Latches {
+				vs[m.Var()] = t.LatchVal(i, d)
+			}
+			s.Eval(vs)
+			for i, m := range gates {
+				dump(gvars[i], vs[m.Var()])
+			}
+		}
+		if d == 0 {
+			fmt.Fprintf(bw, "$end\n")
+		}
+	}
+	fmt.Fprintf(bw, "#%d\n", t.n)
+	return bw.Flush()
+}
+
+// vcdId gives the i'th vcd identifier code, using the printable ascii
+// characters '!' to '~'.
+func vcdId(i int) string {
+	var buf []byte
+	for {
+		buf = append(buf, byte('!'+i%94))
+		i /= 94
+		if i == 0 {
+			break
+		}
+	}
+	return string(buf)
+}
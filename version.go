@@ -0,0 +1,7 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+// Version is the version of reach, which is recorded in stored results.
+const Version = "0.1.0"
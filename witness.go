@@ -0,0 +1,263 @@
+// Copyright 2018 The Reach Authors. All rights reserved.  Use of this source
+// code is governed by a license that can be found in the License file.
+
+package reach
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/logic/aiger"
+	"github.com/go-air/gini/z"
+)
+
+// AigerWitness holds the information in an aiger 1.9 (HWMCC) witness.
+type AigerWitness struct {
+	Status int    // 1=reachable -1=unreachable 0=unknown, as in Result.
+	Props  []int  // indices of the bad state properties.
+	Trace  *Trace // trace, if Status is 1.
+}
+
+// AigerBad returns the bad state literals of `g`, which are the outputs of
+// `g` if `g` has no bad state literals, as in aiger versions before 1.9.
+// Witness property indices refer to this list.
+func AigerBad(g *aiger.T) []z.Lit {
+	if len(g.Bad) == 0 {
+		return g.Outputs
+	}
+	return g.Bad
+}
+
+// EncodeAigerWitness writes `t` as an aiger 1.9 witness for the properties
+// with indices `props` in AigerBad(g).  The witness consists of a status
+// line, a line of property names, a line of initial latch values, one line
+// of input values for each step of `t` and a terminating '.'.
+//
+// Latches and inputs are written in the order of `g`, and are matched to
+// those of `t` by variable.  Latches not in `t` are written with their
+// initial value, or 'x' if uninitialised.  Inputs not in `t` are written
+// as '0'.  An error is returned, and nothing is written, if a property
+// index is not that of a bad state of `g`.
+func (t *Trace) EncodeAigerWitness(w io.Writer, g *aiger.T, props ...int) error {
+	if err := checkAigerProps(g, props); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "1\n")
+	writeAigerProps(bw, props)
+	s := g.Sys()
+	latchIdx := make(map[z.Var]int, len(t.Latches))
+	for i, m := range t.Latches {
+		latchIdx[m.Var()] = i
+	}
+	for _, m := range s.Latches {
+		i, ok := latchIdx[m.Var()]
+		if ok && t.n > 0 {
+			bw.WriteByte(witnessChar(t.LatchVal(i, 0)))
+			continue
+		}
+		switch s.Init(m) {
+		case s.T:
+			bw.WriteByte('1')
+		case s.F:
+			bw.WriteByte('0')
+		default:
+			bw.WriteByte('x')
+		}
+	}
+	bw.WriteByte('\n')
+	inIdx := make(map[z.Var]int, len(t.Inputs))
+	for i, m := range t.Inputs {
+		inIdx[m.Var()] = i
+	}
+	for d := 0; d < t.n; d++ {
+		for _, m := range g.Inputs {
+			i, ok := inIdx[m.Var()]
+			bw.WriteByte(witnessChar(ok && t.InputVal(i, d)))
+		}
+		bw.WriteByte('\n')
+	}
+	fmt.Fprintf(bw, ".\n")
+	return bw.Flush()
+}
+
+// EncodeAigerStatus writes an aiger 1.9 witness without a trace, for
+// unreachable (status -1) or unknown (status 0) properties `props`.
+func EncodeAigerStatus(w io.Writer, status int, props ...int) error {
+	bw := bufio.NewWriter(w)
+	switch status {
+	case -1:
+		fmt.Fprintf(bw, "0\n")
+	case 0:
+		fmt.Fprintf(bw, "2\n")
+	default:
+		return fmt.Errorf("status %d needs a trace", status)
+	}
+	writeAigerProps(bw, props)
+	fmt.Fprintf(bw, ".\n")
+	return bw.Flush()
+}
+
+// checkAigerProps returns an error if some index of `props` is not that of
+// a bad state of `g`.
+func checkAigerProps(g *aiger.T, props []int) error {
+	n := len(AigerBad(g))
+	for _, p := range props {
+		if p < 0 || p >= n {
+			return fmt.Errorf("bad property index %d for %d bad states", p, n)
+		}
+	}
+	return nil
+}
+
+func writeAigerProps(w *bufio.Writer, props []int) {
+	for i, p := range props {
+		if i > 0 {
+			w.WriteByte(' ')
+		}
+		fmt.Fprintf(w, "b%d", p)
+	}
+	w.WriteByte('\n')
+}
+
+func witnessChar(v bool) byte {
+	if v {
+		return '1'
+	}
+	return '0'
+}
+
+// DecodeAigerWitness reads an aiger 1.9 witness for `g`, as written by
+// EncodeAigerWitness or other model checkers.
+//
+// Leading comment lines starting with 'c' are skipped, as is everything
+// after the terminating '.'.  If the witness gives a counterexample, then
+// the returned witness has a trace over all inputs and latches of `g` with
+// the witnessed bad state literals as watches.  The trace is obtained by
+// simulation of `g` from the witness, taking 'x' values as the initial
+// value of a latch if it is initialised and as false otherwise.  It is not
+// verified.
+func DecodeAigerWitness(r io.Reader, g *aiger.T) (*AigerWitness, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<30)
+	line := 0
+	next := func() (string, error) {
+		for sc.Scan() {
+			line++
+			ln := strings.TrimSpace(sc.Text())
+			if ln == "" || ln[0] == 'c' {
+				continue
+			}
+			return ln, nil
+		}
+		if err := sc.Err(); err != nil {
+			return "", err
+		}
+		return "", io.ErrUnexpectedEOF
+	}
+	ln, err := next()
+	if err != nil {
+		return nil, err
+	}
+	res := &AigerWitness{}
+	switch ln {
+	case "1":
+		res.Status = 1
+	case "0":
+		res.Status = -1
+	case "2":
+		res.Status = 0
+	default:
+		return nil, fmt.Errorf("line %d: bad witness status '%s'", line, ln)
+	}
+	ln, err = next()
+	if err != nil {
+		return nil, err
+	}
+	bads := AigerBad(g)
+	for _, f := range strings.Fields(ln) {
+		if f[0] != 'b' {
+			return nil, fmt.Errorf("line %d: unsupported property '%s'", line, f)
+		}
+		p, err := strconv.Atoi(f[1:])
+		if err != nil || p < 0 || p >= len(bads) {
+			return nil, fmt.Errorf("line %d: bad property '%s'", line, f)
+		}
+		res.Props = append(res.Props, p)
+	}
+	if res.Status != 1 {
+		return res, nil
+	}
+	s := g.Sys()
+	ws := make([]z.Lit, len(res.Props))
+	for i, p := range res.Props {
+		ws[i] = bads[p]
+	}
+	trace := NewTrace(s, ws...)
+	vsA, vsB := make([]bool, s.Len()), make([]bool, s.Len())
+	ln, err = next()
+	if err != nil {
+		return nil, err
+	}
+	if len(ln) != len(s.Latches) {
+		return nil, fmt.Errorf("line %d: %d latch values for %d latches",
+			line, len(ln), len(s.Latches))
+	}
+	for i, m := range s.Latches {
+		switch ln[i] {
+		case '0':
+		case '1':
+			vsA[m.Var()] = true
+		case 'x':
+			vsA[m.Var()] = s.Init(m) == s.T
+		default:
+			return nil, fmt.Errorf("line %d: bad latch value '%c'", line, ln[i])
+		}
+	}
+	for {
+		ln, err = next()
+		if err != nil {
+			return nil, err
+		}
+		if ln == "." {
+			break
+		}
+		if len(ln) != len(g.Inputs) {
+			return nil, fmt.Errorf("line %d: %d input values for %d inputs",
+				line, len(ln), len(g.Inputs))
+		}
+		for i, m := range g.Inputs {
+			switch ln[i] {
+			case '0', 'x':
+				vsA[m.Var()] = false
+			case '1':
+				vsA[m.Var()] = true
+			default:
+				return nil, fmt.Errorf("line %d: bad input value '%c'", line, ln[i])
+			}
+		}
+		s.Eval(vsA)
+		trace.Append(vsA)
+		stepLatches(s, vsA, vsB)
+		vsA, vsB = vsB, vsA
+	}
+	res.Trace = trace
+	return res, nil
+}
+
+// stepLatches sets the latches in `dst` to their next state values in
+// `src`.
+func stepLatches(s *logic.S, src, dst []bool) {
+	for _, m := range s.Latches {
+		nxt := s.Next(m)
+		v := src[nxt.Var()]
+		if !nxt.IsPos() {
+			v = !v
+		}
+		dst[m.Var()] = v
+	}
+}